@@ -13,3 +13,80 @@ const (
 	MaxPageSize       = 100
 	RepositoryHost    = "https://github.com"
 )
+
+// 近似图片检测相关
+const (
+	SimilarModeWarn        = "warn"  // 上传后返回近似图片列表
+	SimilarModeReuse       = "reuse" // 存在近似图片时直接复用，不再上传
+	DefaultSimilarDistance = 10      // 默认汉明距离阈值
+	MaxSimilarDistance     = 64
+	MaxSimilarResults      = 50
+)
+
+// 仓库镜像相关
+const (
+	MaxReplicaAttempts   = 5   // 副本写入的最大重试次数
+	ReplicaCatchUpBatch  = 100 // 补偿任务每批处理的副本数
+	ReplicaPendingMinute = 10  // 超过该时间仍为 pending 的副本交给补偿任务处理
+)
+
+// 流式上传未声明大小且没有策略限制时允许读取的最大字节数，与 GitHub release 附件的上限一致
+const MaxStreamUploadSize = 2 << 30
+
+// 大文件相关
+const (
+	LargeFileModeRelease      = "release"
+	LargeFileModeLFS          = "lfs"
+	DefaultLargeFileThreshold = 50 << 20        // 超过该大小的文件不再通过 Contents API 提交
+	LargeFileReleaseTag       = "pichub-assets" // 存放大文件的 release
+	LargeFileLFSDir           = "lfs"           // LFS 文件在仓库中的目录
+)
+
+// 链接健康检查相关
+const (
+	DefaultLinkCheckConcurrency = 8   // 默认并发请求数
+	LinkCheckBatch              = 200 // 每批读取的文件数
+	LinkCheckTimeoutSecond      = 15  // 单个请求超时时间
+)
+
+// 签名地址相关
+const (
+	DefaultSignedURLTTL = 3600          // 默认有效期，秒
+	MaxSignedURLTTL     = 7 * 24 * 3600 // 最长有效期，秒
+)
+
+// 分享链接相关
+const (
+	ShareTokenBytes   = 16              // 分享 token 的随机字节数
+	MaxShareExpiresIn = 365 * 24 * 3600 // 最长有效期，秒
+	ShareRedirectTTL  = 300             // 私有仓库文件跳转时签名地址的有效期，秒
+)
+
+// 相册最大嵌套层数
+const MaxAlbumDepth = 10
+
+// 标签推荐的最大数量
+const MaxTagSuggestions = 20
+
+// 全文搜索相关
+const (
+	SearchReindexBatch  = 200 // 重建索引时每批处理的文件数
+	SearchSnippetRadius = 40  // 高亮片段在匹配位置前保留的字符数
+)
+
+// 文件自定义元数据的最大长度，字节
+const MaxFileMetadataSize = 64 << 10
+
+// 回收站相关
+const (
+	DefaultTrashRetentionDays = 30  // 默认保留天数
+	TrashPurgeBatch           = 100 // 每批彻底删除的文件数
+)
+
+// 批量操作相关
+const (
+	MaxBulkFiles  = 1000 // 单个任务最多处理的文件数
+	BulkItemBatch = 100  // 每批读取的待处理文件数
+
+	BulkErrorMaxLength = 500 // 失败原因的最大长度
+)
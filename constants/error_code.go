@@ -8,6 +8,14 @@ const (
 	ErrCodeForbidden    = 4003
 	ErrCodeNotFound     = 4004
 	ErrCodeServerError  = 5000
+
+	// 上传内容检查
+	ErrCodeUnsafeContent      = 4010
+	ErrCodeFileTypeNotAllowed = 4011
+	ErrCodeFileTooLarge       = 4012
+	ErrCodeImageTooLarge      = 4013
+	ErrCodeQuotaExceeded      = 4014
+	ErrCodePoolFull           = 4015
 )
 
 // 错误信息映射
@@ -18,6 +26,13 @@ var ErrorMessages = map[int]string{
 	ErrCodeForbidden:    "forbidden",
 	ErrCodeNotFound:     "resource not found",
 	ErrCodeServerError:  "internal server error",
+
+	ErrCodeUnsafeContent:      "unsafe file content",
+	ErrCodeFileTypeNotAllowed: "file type not allowed",
+	ErrCodeFileTooLarge:       "file too large",
+	ErrCodeImageTooLarge:      "image dimensions too large",
+	ErrCodeQuotaExceeded:      "storage quota exceeded",
+	ErrCodePoolFull:           "repository pool is full",
 }
 
 // golang 真讨厌啊，抽象程度太低了，连个枚举都没有
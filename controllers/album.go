@@ -0,0 +1,213 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"pichub.api/models"
+	"pichub.api/pkg/validator"
+	"pichub.api/routers/middleware"
+	"pichub.api/services"
+)
+
+// ListAlbums 获取用户的相册，可通过 parent_id 只返回某个相册的子相册
+func ListAlbums(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUser(c)
+
+	var parentID *int
+	if value := c.Query("parent_id"); value != "" {
+		id, err := strconv.Atoi(value)
+		if err != nil || id < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid parent ID"})
+			return
+		}
+		parentID = &id
+	}
+
+	albums, err := services.AlbumService.ListAlbums(userID, parentID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch albums"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"albums": albums})
+}
+
+// GetAlbum 获取相册详情
+func GetAlbum(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUser(c)
+	albumID, ok := parseAlbumID(c)
+	if !ok {
+		return
+	}
+
+	album, err := services.AlbumService.GetAlbumDetail(userID, albumID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Album not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"album": album})
+}
+
+// CreateAlbum 创建相册
+func CreateAlbum(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUser(c)
+
+	var req models.AlbumRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": validator.TranslateErr(err)})
+		return
+	}
+
+	album, err := services.AlbumService.CreateAlbum(userID, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Album created successfully",
+		"album":   album,
+	})
+}
+
+// UpdateAlbum 更新相册
+func UpdateAlbum(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUser(c)
+	albumID, ok := parseAlbumID(c)
+	if !ok {
+		return
+	}
+
+	var req models.AlbumRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": validator.TranslateErr(err)})
+		return
+	}
+
+	album, err := services.AlbumService.UpdateAlbum(userID, albumID, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Album updated successfully",
+		"album":   album,
+	})
+}
+
+// DeleteAlbum 删除相册，相册中的文件不会被删除
+func DeleteAlbum(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUser(c)
+	albumID, ok := parseAlbumID(c)
+	if !ok {
+		return
+	}
+
+	if err := services.AlbumService.DeleteAlbum(userID, albumID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Album deleted successfully"})
+}
+
+// ReorderAlbums 按给定的相册ID顺序排序
+func ReorderAlbums(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUser(c)
+
+	var req models.AlbumOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": validator.TranslateErr(err)})
+		return
+	}
+
+	if err := services.AlbumService.ReorderAlbums(userID, req.IDs); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reorder albums"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Albums reordered successfully"})
+}
+
+// AddAlbumFiles 将文件加入相册
+func AddAlbumFiles(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUser(c)
+	albumID, ok := parseAlbumID(c)
+	if !ok {
+		return
+	}
+
+	var req models.AlbumFilesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": validator.TranslateErr(err)})
+		return
+	}
+
+	added, err := services.AlbumService.AddFiles(userID, albumID, req.FileIDs)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Files added to album successfully",
+		"added":   added,
+	})
+}
+
+// RemoveAlbumFiles 将文件移出相册
+func RemoveAlbumFiles(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUser(c)
+	albumID, ok := parseAlbumID(c)
+	if !ok {
+		return
+	}
+
+	var req models.AlbumFilesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": validator.TranslateErr(err)})
+		return
+	}
+
+	if err := services.AlbumService.RemoveFiles(userID, albumID, req.FileIDs); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Files removed from album successfully"})
+}
+
+// ReorderAlbumFiles 按给定的文件ID顺序排序相册中的文件
+func ReorderAlbumFiles(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUser(c)
+	albumID, ok := parseAlbumID(c)
+	if !ok {
+		return
+	}
+
+	var req models.AlbumOrderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": validator.TranslateErr(err)})
+		return
+	}
+
+	if err := services.AlbumService.ReorderFiles(userID, albumID, req.IDs); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Album files reordered successfully"})
+}
+
+func parseAlbumID(c *gin.Context) (int, bool) {
+	albumID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || albumID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid album ID"})
+		return 0, false
+	}
+	return albumID, true
+}
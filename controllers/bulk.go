@@ -0,0 +1,89 @@
+package controllers
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"pichub.api/models"
+	"pichub.api/pkg/validator"
+	"pichub.api/routers/middleware"
+	"pichub.api/services"
+)
+
+// CreateBulkJob 创建批量操作任务，任务在后台执行，通过 GetBulkJob 查询进度
+func CreateBulkJob(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUser(c)
+
+	var req models.BulkFilesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": validator.TranslateErr(err)})
+		return
+	}
+
+	job, err := services.BulkService.Create(userID, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "Bulk job started",
+		"job":     job,
+	})
+}
+
+// ListBulkJobs 分页获取批量操作任务
+func ListBulkJobs(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUser(c)
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	jobs, total, err := services.BulkService.List(userID, page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch bulk jobs"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"jobs": jobs,
+		"pagination": gin.H{
+			"current_page": page,
+			"page_size":    pageSize,
+			"total":        total,
+			"total_pages":  int(math.Ceil(float64(total) / float64(pageSize))),
+			"has_more":     page*pageSize < int(total),
+		},
+	})
+}
+
+// GetBulkJob 获取任务进度和每个文件的处理结果
+func GetBulkJob(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUser(c)
+	jobID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || jobID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid job ID"})
+		return
+	}
+
+	job, err := services.BulkService.Get(userID, jobID)
+	if err != nil {
+		if errors.Is(err, services.ErrBulkJobNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch bulk job"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"job": job})
+}
@@ -1,13 +1,20 @@
 package controllers
 
 import (
+	"errors"
+	"fmt"
 	"math"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"pichub.api/constants"
 	"pichub.api/infra/database"
 	"pichub.api/models"
+	"pichub.api/pkg/utils"
+	"pichub.api/pkg/validator"
 	"pichub.api/routers/middleware"
 	"pichub.api/services"
 )
@@ -16,10 +23,16 @@ import (
 func UploadFile(c *gin.Context) {
 	userID, _ := middleware.GetCurrentUser(c)
 
-	// 获取仓库ID
-	repoID, err := strconv.Atoi(c.PostForm("repo_id"))
+	// 获取上传的文件
+	file, err := c.FormFile("file")
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid repository ID"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No file uploaded"})
+		return
+	}
+
+	// 获取仓库ID，指定仓库池时由仓库池选择仓库
+	repoID, ok := resolveUploadRepoID(c, userID, c.PostForm("repo_id"), c.PostForm("pool_id"), file.Size)
+	if !ok {
 		return
 	}
 
@@ -30,30 +43,30 @@ func UploadFile(c *gin.Context) {
 		return
 	}
 
-	// 获取上传的文件
-	file, err := c.FormFile("file")
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "No file uploaded"})
-		return
-	}
-
 	// 检查是否强制上传
 	isForceParam := c.PostForm("is_force")
-	isForce := isForceParam == "true" || isForceParam == "1"
+	opts := models.UploadOptions{
+		IsForce:   isForceParam == "true" || isForceParam == "1",
+		OnSimilar: c.PostForm("on_similar"),
+		Watermark: parseOptionalBool(c.PostForm("watermark")),
+	}
 
 	// 处理文件上传
-	uploadedFile, err := services.FileService.UploadFile(file, userID, repoID, isForce)
+	uploadedFile, err := services.FileService.UploadFile(file, userID, repoID, opts)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondUploadError(c, err)
 		return
 	}
 
-	cdnHost := services.ConfigService.GetFileCDNHostname(0)
-
-	c.JSON(http.StatusOK, gin.H{
+	response := gin.H{
 		"message": "File uploaded successfully",
-		"file":    uploadedFile.ToResponse(cdnHost),
-	})
+		"file":    services.FileService.ToResponses([]models.File{*uploadedFile})[0],
+	}
+	if opts.OnSimilar == constants.SimilarModeWarn {
+		response["similar_files"] = similarFilesResponse(userID, uploadedFile)
+	}
+
+	c.JSON(http.StatusOK, response)
 }
 
 // ListFiles 获取用户的所有文件
@@ -72,27 +85,33 @@ func ListFiles(c *gin.Context) {
 		pageSize = 10
 	}
 
-	// 获取仓库ID（如果有）
-	repoID := 0
-	if repoIDStr := c.Query("repo_id"); repoIDStr != "" {
-		repoID, _ = strconv.Atoi(repoIDStr)
+	// 获取筛选条件（如果有）
+	filter, err := parseFileFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
 	// 使用服务获取文件列表
-	files, total, err := services.FileService.ListFiles(userID, repoID, page, pageSize)
+	files, total, err := services.FileService.ListFiles(userID, filter, page, pageSize)
 	if err != nil {
+		if errors.Is(err, services.ErrInvalidCursor) || errors.Is(err, services.ErrCursorUnsupported) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch files"})
 		return
 	}
 
 	// 构建响应
-	var response []models.FileResponse
-	cdnHost := services.ConfigService.GetFileCDNHostname(0)
-	for _, file := range files {
-		response = append(response, file.ToResponse(cdnHost))
-	}
+	response := services.FileService.ToResponses(files)
 
+	// 游标分页时以是否有下一页游标判断
+	nextCursor := services.FileService.NextCursor(filter, files, pageSize)
 	hasMore := page*pageSize < int(total)
+	if filter.Cursor != "" {
+		hasMore = nextCursor != ""
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"files": response,
@@ -102,10 +121,101 @@ func ListFiles(c *gin.Context) {
 			"total":        total,
 			"total_pages":  int(math.Ceil(float64(total) / float64(pageSize))),
 			"has_more":     hasMore,
+			"next_cursor":  nextCursor,
 		},
 	})
 }
 
+// parseFileFilter 解析文件列表的筛选、排序和游标参数
+func parseFileFilter(c *gin.Context) (models.FileFilter, error) {
+	var filter models.FileFilter
+	filter.RepoID, _ = strconv.Atoi(c.Query("repo_id"))
+	filter.AlbumID, _ = strconv.Atoi(c.Query("album_id"))
+	filter.Keyword = strings.TrimSpace(c.Query("keyword"))
+	filter.Mime = strings.TrimSpace(c.Query("mime"))
+	filter.Cursor = c.Query("cursor")
+
+	if tags := c.Query("tags"); tags != "" {
+		filter.Tags = strings.Split(tags, ",")
+	}
+	filter.TagMode = c.DefaultQuery("tag_mode", models.TagMatchAll)
+	if filter.TagMode != models.TagMatchAll && filter.TagMode != models.TagMatchAny {
+		return filter, errors.New("tag_mode must be and or or")
+	}
+
+	if filetypes := c.Query("filetype"); filetypes != "" {
+		for _, value := range strings.Split(filetypes, ",") {
+			code, err := strconv.ParseUint(strings.TrimSpace(value), 10, 8)
+			if err != nil {
+				return filter, errors.New("invalid filetype")
+			}
+			filter.Filetypes = append(filter.Filetypes, int(code))
+		}
+	}
+
+	ranges := map[string]*uint{
+		"min_size":   &filter.MinSize,
+		"max_size":   &filter.MaxSize,
+		"min_width":  &filter.MinWidth,
+		"max_width":  &filter.MaxWidth,
+		"min_height": &filter.MinHeight,
+		"max_height": &filter.MaxHeight,
+	}
+	for name, target := range ranges {
+		if value := c.Query(name); value != "" {
+			number, err := strconv.ParseUint(value, 10, 32)
+			if err != nil {
+				return filter, fmt.Errorf("invalid %s", name)
+			}
+			*target = uint(number)
+		}
+	}
+
+	// 只有日期的结束时间包含当天
+	if value := c.Query("created_from"); value != "" {
+		from, _, err := parseFilterTime(value)
+		if err != nil {
+			return filter, errors.New("invalid created_from")
+		}
+		filter.CreatedFrom = &from
+	}
+	if value := c.Query("created_to"); value != "" {
+		to, dateOnly, err := parseFilterTime(value)
+		if err != nil {
+			return filter, errors.New("invalid created_to")
+		}
+		if dateOnly {
+			to = to.AddDate(0, 0, 1)
+		}
+		filter.CreatedTo = &to
+	}
+
+	filter.Sort = c.Query("sort")
+	switch filter.Sort {
+	case models.FileSortDefault, models.FileSortDate, models.FileSortSize, models.FileSortName:
+	default:
+		return filter, errors.New("sort must be date, size or name")
+	}
+	switch order := c.Query("order"); order {
+	case "", "desc":
+	case "asc":
+		filter.Asc = true
+	default:
+		return filter, errors.New("order must be asc or desc")
+	}
+
+	return filter, nil
+}
+
+// parseFilterTime 解析 RFC3339 时间或 2006-01-02 格式的日期
+func parseFilterTime(value string) (time.Time, bool, error) {
+	if t, err := time.ParseInLocation(time.DateOnly, value, time.Local); err == nil {
+		return t, true, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	return t, false, err
+}
+
 // DeleteFile 删除文件
 func DeleteFile(c *gin.Context) {
 	userID, _ := middleware.GetCurrentUser(c)
@@ -137,17 +247,32 @@ func DeleteFile(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "File deleted successfully"})
+	c.JSON(http.StatusOK, gin.H{"message": "File moved to trash"})
 }
 
 // UploadStream 处理流式文件上传请求
 func UploadStream(c *gin.Context) {
 	userID, _ := middleware.GetCurrentUser(c)
 
-	// 获取仓库ID
-	repoID, err := strconv.Atoi(c.Param("repo_id"))
+	// 获取文件信息从请求头
+	filename := c.GetHeader("X-File-Name")
+	contentType := c.GetHeader("Content-Type")
+	contentLength := c.GetHeader("Content-Length")
+	opts := models.UploadOptions{
+		IsForce:   c.GetHeader("X-Is-Force") == "true",
+		OnSimilar: c.GetHeader("X-On-Similar"),
+		Watermark: parseOptionalBool(c.GetHeader("X-Watermark")),
+	}
+
+	fileSize, err := strconv.ParseInt(contentLength, 10, 64)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid repository ID"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Content-Length"})
+		return
+	}
+
+	// 获取仓库ID，通过 pool_id 查询参数指定仓库池时由仓库池选择仓库
+	repoID, ok := resolveUploadRepoID(c, userID, c.Param("repo_id"), c.Query("pool_id"), fileSize)
+	if !ok {
 		return
 	}
 
@@ -158,29 +283,253 @@ func UploadStream(c *gin.Context) {
 		return
 	}
 
-	// 获取文件信息从请求头
-	filename := c.GetHeader("X-File-Name")
-	contentType := c.GetHeader("Content-Type")
-	contentLength := c.GetHeader("Content-Length")
-	isForce := c.GetHeader("X-Is-Force") == "true"
+	// 处理文件上传
+	uploadedFile, err := services.FileService.UploadStream(c.Request.Body, filename, contentType, fileSize, userID, repoID, opts)
+	if err != nil {
+		respondUploadError(c, err)
+		return
+	}
 
-	fileSize, err := strconv.ParseInt(contentLength, 10, 64)
+	response := gin.H{
+		"message": "File uploaded successfully",
+		"file":    services.FileService.ToResponses([]models.File{*uploadedFile})[0],
+	}
+	if opts.OnSimilar == constants.SimilarModeWarn {
+		response["similar_files"] = similarFilesResponse(userID, uploadedFile)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// UpdateFileMetadata 更新文件的标题、替代文本、描述和自定义元数据
+func UpdateFileMetadata(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUser(c)
+
+	fileID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || fileID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file ID"})
+		return
+	}
+
+	var req models.UpdateFileMetadataRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": validator.TranslateErr(err)})
+		return
+	}
+
+	file, err := services.FileService.UpdateMetadata(userID, fileID, req)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Content-Length"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// 处理文件上传
-	uploadedFile, err := services.FileService.UploadStream(c.Request.Body, filename, contentType, fileSize, userID, repoID, isForce)
+	c.JSON(http.StatusOK, gin.H{
+		"message": "File metadata updated successfully",
+		"file":    services.FileService.ToResponses([]models.File{*file})[0],
+	})
+}
+
+// MoveFile 修改文件路径或移动到另一个仓库
+func MoveFile(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUser(c)
+
+	fileID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || fileID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file ID"})
+		return
+	}
+
+	var req models.MoveFileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": validator.TranslateErr(err)})
+		return
+	}
+	if req.Path == "" && req.RepoID == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "path or repo_id is required"})
+		return
+	}
+
+	file, err := services.FileService.MoveFile(userID, fileID, req)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		// 超出目标仓库配额时返回带错误码的拒绝原因
+		var rejected *services.UploadRejectedError
+		if errors.As(err, &rejected) {
+			respondUploadError(c, err)
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "File moved successfully",
+		"file":    services.FileService.ToResponses([]models.File{*file})[0],
+	})
+}
+
+// RedirectMovedFile 公开接口，将移动前的地址跳转到文件当前的地址
+// 只有访问 /api/v1/r/:repo_id/*path 才会跳转，旧的 CDN 或 raw 地址仍然失效
+// 使用自定义域名时，可通过反向代理将旧地址转发到这里，路径与仓库中的路径一致
+// 文件已在私有仓库中时不跳转，避免公开地址访问到私有文件
+func RedirectMovedFile(c *gin.Context) {
+	repoID, err := strconv.Atoi(c.Param("repo_id"))
+	if err != nil || repoID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid repository ID"})
+		return
+	}
+
+	file, err := services.FileService.ResolveRedirect(repoID, c.Param("path"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	urls, repo := services.FileService.FileURLs(file)
+	if repo != nil && repo.Private {
+		c.JSON(http.StatusNotFound, gin.H{"error": services.ErrRedirectNotFound.Error()})
+		return
+	}
+
+	// 文件可能再次移动，不使用永久跳转
+	c.Header("Cache-Control", "no-store")
+	c.Redirect(http.StatusFound, urls[0])
+}
+
+// GetFileLinks 获取文件的各种引用代码，包括内置格式和用户自定义模板
+// 指定 format 时只返回该格式
+func GetFileLinks(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUser(c)
+
+	fileID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || fileID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file ID"})
+		return
+	}
+
+	file, err := services.FileService.GetFile(userID, fileID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
 		return
 	}
 
-	cdnHost := services.ConfigService.GetFileCDNHostname(0)
+	links := services.FileService.ToResponses([]models.File{*file})[0].LinkFormats()
+	if format := c.Query("format"); format != "" {
+		link, ok := links[format]
+		if !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Unknown link format %s", format)})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"format": format, "link": link})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"links": links})
+}
+
+// FindSimilarFiles 查找与指定图片近似的文件
+func FindSimilarFiles(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUser(c)
+
+	fileID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || fileID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file ID"})
+		return
+	}
+
+	// 汉明距离阈值，未指定时使用配置值
+	distance := services.FileService.GetSimilarDistance(userID)
+	if distanceStr := c.Query("distance"); distanceStr != "" {
+		distance, err = strconv.Atoi(distanceStr)
+		if err != nil || distance < 0 || distance > constants.MaxSimilarDistance {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid distance"})
+			return
+		}
+	}
+
+	files, phash, err := services.FileService.FindSimilarFiles(userID, fileID, distance)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	response := []models.SimilarFileResponse{}
+	for i, item := range services.FileService.ToResponses(files) {
+		response = append(response, models.SimilarFileResponse{
+			FileResponse: item,
+			Distance:     utils.HammingDistance(phash, files[i].Phash),
+		})
+	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": "File uploaded successfully",
-		"file":    uploadedFile.ToResponse(cdnHost),
+		"files":    response,
+		"distance": distance,
 	})
 }
+
+// similarFilesResponse 上传提示模式下，返回刚上传文件的近似图片列表
+func similarFilesResponse(userID int, file *models.File) []models.SimilarFileResponse {
+	response := []models.SimilarFileResponse{}
+	if file.Phash == 0 {
+		return response
+	}
+
+	files, _, err := services.FileService.FindSimilarFiles(userID, file.ID, services.FileService.GetSimilarDistance(userID))
+	if err != nil {
+		return response
+	}
+
+	for i, item := range services.FileService.ToResponses(files) {
+		response = append(response, models.SimilarFileResponse{
+			FileResponse: item,
+			Distance:     utils.HammingDistance(file.Phash, files[i].Phash),
+		})
+	}
+	return response
+}
+
+// parseOptionalBool 解析可选的布尔参数，未传或无法解析时返回 nil
+func parseOptionalBool(value string) *bool {
+	if value == "" {
+		return nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil
+	}
+	return &b
+}
+
+// respondUploadError 返回上传失败的响应，内容被拒绝时带上错误码和原因
+func respondUploadError(c *gin.Context, err error) {
+	var rejected *services.UploadRejectedError
+	if errors.As(err, &rejected) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": rejected.Reason, "code": rejected.Code})
+		return
+	}
+	c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+}
+
+// resolveUploadRepoID 解析上传目标仓库，指定仓库池时选择池中当前可用的仓库
+func resolveUploadRepoID(c *gin.Context, userID int, repoIDStr string, poolIDStr string, size int64) (int, bool) {
+	if poolIDStr != "" {
+		poolID, err := strconv.Atoi(poolIDStr)
+		if err != nil || poolID <= 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pool ID"})
+			return 0, false
+		}
+
+		repoID, err := services.RepoPoolService.ResolveRepository(userID, poolID, size)
+		if err != nil {
+			respondUploadError(c, err)
+			return 0, false
+		}
+		return repoID, true
+	}
+
+	repoID, err := strconv.Atoi(repoIDStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid repository ID"})
+		return 0, false
+	}
+	return repoID, true
+}
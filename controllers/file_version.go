@@ -0,0 +1,101 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"pichub.api/models"
+	"pichub.api/routers/middleware"
+	"pichub.api/services"
+)
+
+// ReplaceFile 上传新内容覆盖文件，路径和访问地址不变，旧内容保存为历史版本
+func ReplaceFile(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUser(c)
+
+	fileID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || fileID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file ID"})
+		return
+	}
+
+	upload, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "No file uploaded"})
+		return
+	}
+
+	opts := models.UploadOptions{Watermark: parseOptionalBool(c.PostForm("watermark"))}
+	file, err := services.FileService.ReplaceFile(userID, fileID, upload, opts)
+	if err != nil {
+		respondVersionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "File replaced successfully",
+		"file":    services.FileService.ToResponses([]models.File{*file})[0],
+	})
+}
+
+// ListFileVersions 获取文件的历史版本
+func ListFileVersions(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUser(c)
+
+	fileID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || fileID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file ID"})
+		return
+	}
+
+	versions, err := services.FileService.ListVersions(userID, fileID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"versions": versions})
+}
+
+// RollbackFile 将文件恢复为指定的历史版本
+func RollbackFile(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUser(c)
+
+	fileID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || fileID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file ID"})
+		return
+	}
+	versionID, err := strconv.Atoi(c.Param("version_id"))
+	if err != nil || versionID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid version ID"})
+		return
+	}
+
+	file, err := services.FileService.RollbackFile(userID, fileID, versionID)
+	if err != nil {
+		if errors.Is(err, services.ErrFileVersionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		respondVersionError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "File rolled back successfully",
+		"file":    services.FileService.ToResponses([]models.File{*file})[0],
+	})
+}
+
+// respondVersionError 内容未通过检查或超出配额时返回带错误码的拒绝原因
+func respondVersionError(c *gin.Context, err error) {
+	var rejected *services.UploadRejectedError
+	if errors.As(err, &rejected) {
+		respondUploadError(c, err)
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
@@ -0,0 +1,57 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"pichub.api/routers/middleware"
+	"pichub.api/services"
+)
+
+// GetLinkReport 获取链接健康检查报告，包含汇总和失效链接列表
+func GetLinkReport(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUser(c)
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	summary, broken, err := services.LinkCheckService.Report(userID, page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch link report"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"summary": summary,
+		"broken":  broken,
+		"pagination": gin.H{
+			"current_page": page,
+			"page_size":    pageSize,
+			"has_more":     page*pageSize < int(summary.BrokenLinks),
+		},
+	})
+}
+
+// RunLinkCheck 在后台检查当前用户所有文件的访问地址
+func RunLinkCheck(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUser(c)
+
+	if err := services.LinkCheckService.Start(userID); err != nil {
+		if errors.Is(err, services.ErrLinkCheckRunning) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Link check is already running"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start link check"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Link check started"})
+}
@@ -0,0 +1,144 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"pichub.api/infra/logger"
+	"pichub.api/models"
+	"pichub.api/pkg/validator"
+	"pichub.api/routers/middleware"
+	"pichub.api/services"
+)
+
+// ListRepoMirrors 获取仓库的镜像及副本同步统计
+func ListRepoMirrors(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUser(c)
+	repoID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid repository ID"})
+		return
+	}
+
+	mirrors, err := services.MirrorService.ListMirrors(userID, repoID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch mirrors"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"mirrors": mirrors})
+}
+
+// CreateRepoMirror 为仓库添加镜像
+func CreateRepoMirror(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUser(c)
+	repoID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid repository ID"})
+		return
+	}
+
+	var req models.RepoMirrorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": validator.TranslateErr(err)})
+		return
+	}
+
+	mirror, err := services.MirrorService.CreateMirror(userID, repoID, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Mirror created successfully",
+		"mirror":  mirror,
+	})
+}
+
+// UpdateRepoMirror 更新镜像设置
+func UpdateRepoMirror(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUser(c)
+	mirrorID, ok := parseMirrorID(c, userID)
+	if !ok {
+		return
+	}
+
+	var req models.RepoMirrorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": validator.TranslateErr(err)})
+		return
+	}
+
+	mirror, err := services.MirrorService.UpdateMirror(userID, mirrorID, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Mirror updated successfully",
+		"mirror":  mirror,
+	})
+}
+
+// DeleteRepoMirror 删除镜像，镜像中已有的文件保留
+func DeleteRepoMirror(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUser(c)
+	mirrorID, ok := parseMirrorID(c, userID)
+	if !ok {
+		return
+	}
+
+	if err := services.MirrorService.DeleteMirror(userID, mirrorID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete mirror"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Mirror deleted successfully"})
+}
+
+// SyncRepoMirrors 在后台对仓库的镜像执行一次补偿同步
+func SyncRepoMirrors(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUser(c)
+	repoID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid repository ID"})
+		return
+	}
+
+	if _, err := services.RepositoryService.GetRepository(userID, repoID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Repository not found"})
+		return
+	}
+
+	go func() {
+		if err := services.MirrorService.CatchUp(repoID); err != nil {
+			logger.Errorf("sync mirrors of repository %d failed: %v", repoID, err)
+		}
+	}()
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "Mirror sync started"})
+}
+
+// parseMirrorID 解析镜像ID并校验镜像属于路径中的仓库
+func parseMirrorID(c *gin.Context, userID int) (int, bool) {
+	repoID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid repository ID"})
+		return 0, false
+	}
+	mirrorID, err := strconv.Atoi(c.Param("mirror_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid mirror ID"})
+		return 0, false
+	}
+
+	mirror, err := services.MirrorService.GetMirror(userID, mirrorID)
+	if err != nil || mirror.RepoID != repoID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Mirror not found"})
+		return 0, false
+	}
+	return mirrorID, true
+}
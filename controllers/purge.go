@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"pichub.api/routers/middleware"
+	"pichub.api/services"
+)
+
+// PurgeFile 手动刷新文件的 CDN 缓存
+func PurgeFile(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUser(c)
+	fileID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || fileID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file ID"})
+		return
+	}
+
+	file, err := services.FileService.GetFile(userID, fileID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		return
+	}
+
+	logs, err := services.PurgeService.PurgeFile(file, services.PurgeReasonManual)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Purge finished",
+		"logs":    logs,
+	})
+}
+
+// ListPurgeLogs 获取 CDN 缓存刷新日志
+func ListPurgeLogs(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUser(c)
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+	fileID, _ := strconv.Atoi(c.Query("file_id"))
+
+	logs, total, err := services.PurgeService.ListLogs(userID, fileID, page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch purge logs"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"logs": logs,
+		"pagination": gin.H{
+			"current_page": page,
+			"page_size":    pageSize,
+			"total":        total,
+			"total_pages":  int(math.Ceil(float64(total) / float64(pageSize))),
+			"has_more":     page*pageSize < int(total),
+		},
+	})
+}
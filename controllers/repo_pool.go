@@ -0,0 +1,155 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"pichub.api/models"
+	"pichub.api/pkg/validator"
+	"pichub.api/routers/middleware"
+	"pichub.api/services"
+)
+
+// ListRepoPools 获取用户的仓库池
+func ListRepoPools(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUser(c)
+
+	pools, err := services.RepoPoolService.ListPools(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch repository pools"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"pools": pools})
+}
+
+// GetRepoPool 获取仓库池详情，包含成员仓库的当前容量
+func GetRepoPool(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUser(c)
+	poolID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pool ID"})
+		return
+	}
+
+	pool, err := services.RepoPoolService.GetPoolDetail(userID, poolID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Repository pool not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"pool": pool})
+}
+
+// CreateRepoPool 创建仓库池
+func CreateRepoPool(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUser(c)
+
+	var req models.RepoPoolRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": validator.TranslateErr(err)})
+		return
+	}
+
+	pool, err := services.RepoPoolService.CreatePool(userID, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Repository pool created successfully",
+		"pool":    pool,
+	})
+}
+
+// UpdateRepoPool 更新仓库池设置
+func UpdateRepoPool(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUser(c)
+	poolID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pool ID"})
+		return
+	}
+
+	var req models.RepoPoolRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": validator.TranslateErr(err)})
+		return
+	}
+
+	pool, err := services.RepoPoolService.UpdatePool(userID, poolID, req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update repository pool"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Repository pool updated successfully",
+		"pool":    pool,
+	})
+}
+
+// DeleteRepoPool 删除仓库池，成员仓库保留
+func DeleteRepoPool(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUser(c)
+	poolID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pool ID"})
+		return
+	}
+
+	if err := services.RepoPoolService.DeletePool(userID, poolID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete repository pool"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Repository pool deleted successfully"})
+}
+
+// AddRepoPoolMember 将仓库加入仓库池
+func AddRepoPoolMember(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUser(c)
+	poolID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pool ID"})
+		return
+	}
+
+	var req models.RepoPoolMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": validator.TranslateErr(err)})
+		return
+	}
+
+	if err := services.RepoPoolService.AddMember(userID, poolID, req.RepoID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Repository added to pool successfully"})
+}
+
+// RemoveRepoPoolMember 将仓库移出仓库池
+func RemoveRepoPoolMember(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUser(c)
+	poolID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pool ID"})
+		return
+	}
+
+	var req models.RepoPoolMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": validator.TranslateErr(err)})
+		return
+	}
+
+	if err := services.RepoPoolService.RemoveMember(userID, poolID, req.RepoID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Repository removed from pool successfully"})
+}
@@ -1,6 +1,7 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -37,14 +38,30 @@ func AddRepository(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Repository added successfully",
-		"repository": models.RepositoryResponse{
-			ID:         repository.ID,
-			RepoName:   repository.RepoName,
-			RepoURL:    repository.RepoURL,
-			RepoBranch: repository.RepoBranch,
-			CreatedAt:  repository.CreatedAt,
-		},
+		"message":    "Repository added successfully",
+		"repository": repository.ToResponse(),
+	})
+}
+
+// CreateRepository 在GitHub上创建新仓库并添加到 PicHub
+func CreateRepository(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUser(c)
+
+	var req models.CreateRepositoryRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": validator.TranslateErr(err)})
+		return
+	}
+
+	repository, err := services.RepositoryService.CreateRepository(userID, req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Repository created successfully",
+		"repository": repository.ToResponse(),
 	})
 }
 
@@ -80,13 +97,7 @@ func ListRepositories(c *gin.Context) {
 
 	var response []models.RepositoryResponse
 	for _, repo := range repositories {
-		response = append(response, models.RepositoryResponse{
-			ID:         repo.ID,
-			RepoName:   repo.RepoName,
-			RepoBranch: repo.RepoBranch,
-			RepoURL:    repo.RepoURL,
-			CreatedAt:  repo.CreatedAt,
-		})
+		response = append(response, repo.ToResponse())
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -110,13 +121,7 @@ func GetRepository(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"repository": models.RepositoryResponse{
-			ID:         repository.ID,
-			RepoName:   repository.RepoName,
-			RepoBranch: repository.RepoBranch,
-			RepoURL:    repository.RepoURL,
-			CreatedAt:  repository.CreatedAt,
-		},
+		"repository": repository.ToResponse(),
 	})
 }
 
@@ -145,7 +150,39 @@ func UpdateRepository(c *gin.Context) {
 	})
 }
 
-// DeleteRepository 删除仓库
+// ListURLPresets 获取可用的访问地址预设模板
+func ListURLPresets(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"presets": models.URLPresets})
+}
+
+// SetRepositoryURLTemplates 设置仓库的访问地址模板
+func SetRepositoryURLTemplates(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUser(c)
+	repoID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid repository ID"})
+		return
+	}
+
+	var req models.RepositoryURLTemplateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": validator.TranslateErr(err)})
+		return
+	}
+
+	repository, err := services.RepositoryService.SetURLTemplates(userID, repoID, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Repository url templates updated successfully",
+		"repository": repository.ToResponse(),
+	})
+}
+
+// DeleteRepository 删除仓库，仓库中还有文件时返回 409
 func DeleteRepository(c *gin.Context) {
 	userID, _ := middleware.GetCurrentUser(c)
 	repoID, err := strconv.Atoi(c.Param("id"))
@@ -155,6 +192,10 @@ func DeleteRepository(c *gin.Context) {
 	}
 
 	if err := services.RepositoryService.DeleteRepository(userID, repoID); err != nil {
+		if errors.Is(err, services.ErrRepositoryNotEmpty) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete repository"})
 		return
 	}
@@ -0,0 +1,69 @@
+package controllers
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"pichub.api/routers/middleware"
+	"pichub.api/services"
+)
+
+// SearchFiles 全文搜索文件，支持与文件列表相同的筛选参数
+func SearchFiles(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUser(c)
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	filter, err := parseFileFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	results, total, err := services.SearchService.Search(userID, c.Query("q"), filter, page, pageSize)
+	if err != nil {
+		if errors.Is(err, services.ErrEmptySearchQuery) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search files"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"results": results,
+		"pagination": gin.H{
+			"current_page": page,
+			"page_size":    pageSize,
+			"total":        total,
+			"total_pages":  int(math.Ceil(float64(total) / float64(pageSize))),
+			"has_more":     page*pageSize < int(total),
+		},
+	})
+}
+
+// RebuildSearchIndex 重建当前用户所有文件的搜索索引
+func RebuildSearchIndex(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUser(c)
+
+	indexed, err := services.SearchService.Rebuild(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to rebuild search index"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Search index rebuilt successfully",
+		"indexed": indexed,
+	})
+}
@@ -0,0 +1,320 @@
+package controllers
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"pichub.api/constants"
+	"pichub.api/infra/logger"
+	"pichub.api/models"
+	"pichub.api/pkg/validator"
+	"pichub.api/routers/middleware"
+	"pichub.api/services"
+)
+
+// CreateShare 为文件或相册创建分享链接
+func CreateShare(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUser(c)
+
+	var req models.CreateShareRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": validator.TranslateErr(err)})
+		return
+	}
+
+	share, err := services.ShareService.Create(userID, req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, services.ShareService.ToResponses([]models.Share{*share})[0])
+}
+
+// ListShares 获取分享链接列表
+func ListShares(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUser(c)
+	page, pageSize := sharePagination(c)
+
+	shares, total, err := services.ShareService.List(userID, page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch shares"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"shares": services.ShareService.ToResponses(shares),
+		"pagination": gin.H{
+			"current_page": page,
+			"page_size":    pageSize,
+			"total":        total,
+			"total_pages":  int(math.Ceil(float64(total) / float64(pageSize))),
+			"has_more":     page*pageSize < int(total),
+		},
+	})
+}
+
+// RevokeShare 撤销分享链接
+func RevokeShare(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUser(c)
+	shareID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || shareID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid share ID"})
+		return
+	}
+
+	if err := services.ShareService.Revoke(userID, shareID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Share revoked successfully"})
+}
+
+// ListShareLogs 获取分享链接的访问记录
+func ListShareLogs(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUser(c)
+	shareID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || shareID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid share ID"})
+		return
+	}
+	page, pageSize := sharePagination(c)
+
+	logs, total, err := services.ShareService.ListLogs(userID, shareID, page, pageSize)
+	if err != nil {
+		if errors.Is(err, services.ErrShareNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch share logs"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"logs": logs,
+		"pagination": gin.H{
+			"current_page": page,
+			"page_size":    pageSize,
+			"total":        total,
+			"total_pages":  int(math.Ceil(float64(total) / float64(pageSize))),
+			"has_more":     page*pageSize < int(total),
+		},
+	})
+}
+
+// GetShareInfo 公开接口，返回分享的基本信息，不计入下载次数
+func GetShareInfo(c *gin.Context) {
+	share, err := services.ShareService.Find(c.Param("token"))
+	if err != nil {
+		respondShareError(c, err)
+		return
+	}
+
+	info := gin.H{
+		"target_type":       share.TargetType,
+		"expires_at":        share.ExpiresAt,
+		"password_required": share.PasswordHash != "",
+		"max_downloads":     share.MaxDownloads,
+		"download_count":    share.DownloadCount,
+	}
+
+	// 设置了密码的分享不泄露分享内容
+	if share.PasswordHash == "" {
+		if share.TargetType == models.ShareTargetAlbum {
+			album, err := services.AlbumService.GetAlbumDetail(share.UserID, share.TargetID)
+			if err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Album not found"})
+				return
+			}
+			info["album"] = gin.H{
+				"name":        album.Name,
+				"description": album.Description,
+				"file_count":  album.FileCount,
+			}
+		} else {
+			file, err := services.ShareService.SharedFile(share, 0)
+			if err != nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+				return
+			}
+			info["file"] = sharedFileInfo(file)
+		}
+	}
+
+	c.JSON(http.StatusOK, info)
+}
+
+// AccessShare 公开接口，文件分享跳转到文件地址或代理返回文件内容，相册分享返回文件列表
+// 密码可通过 password 查询参数或 X-Share-Password 请求头提供
+func AccessShare(c *gin.Context) {
+	visitor := shareVisitor(c)
+	share, err := services.ShareService.Open(c.Param("token"), sharePassword(c), visitor)
+	if err != nil {
+		respondShareError(c, err)
+		return
+	}
+
+	if share.TargetType == models.ShareTargetAlbum {
+		listSharedAlbum(c, share)
+		return
+	}
+
+	downloadSharedFile(c, share, 0, visitor)
+}
+
+// AccessShareFile 公开接口，下载相册分享中的单个文件
+func AccessShareFile(c *gin.Context) {
+	fileID, err := strconv.Atoi(c.Param("file_id"))
+	if err != nil || fileID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file ID"})
+		return
+	}
+
+	visitor := shareVisitor(c)
+	share, err := services.ShareService.Open(c.Param("token"), sharePassword(c), visitor)
+	if err != nil {
+		respondShareError(c, err)
+		return
+	}
+
+	downloadSharedFile(c, share, fileID, visitor)
+}
+
+// listSharedAlbum 分页返回相册分享中的文件，列表不计入下载次数
+func listSharedAlbum(c *gin.Context, share *models.Share) {
+	album, err := services.AlbumService.GetAlbum(share.UserID, share.TargetID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Album not found"})
+		return
+	}
+
+	page, pageSize := sharePagination(c)
+	files, total, err := services.FileService.ListFiles(share.UserID, models.FileFilter{AlbumID: album.ID}, page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch files"})
+		return
+	}
+
+	items := []gin.H{}
+	for i := range files {
+		item := sharedFileInfo(&files[i])
+		item["id"] = files[i].ID
+		item["url"] = services.ShareService.FileURL(share.Token, files[i].ID)
+		items = append(items, item)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"album": gin.H{
+			"name":        album.Name,
+			"description": album.Description,
+		},
+		"files": items,
+		"pagination": gin.H{
+			"current_page": page,
+			"page_size":    pageSize,
+			"total":        total,
+			"total_pages":  int(math.Ceil(float64(total) / float64(pageSize))),
+			"has_more":     page*pageSize < int(total),
+		},
+	})
+}
+
+// downloadSharedFile 计入下载次数后返回分享中的文件
+func downloadSharedFile(c *gin.Context, share *models.Share, fileID int, visitor models.ShareAccessLog) {
+	file, err := services.ShareService.SharedFile(share, fileID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		return
+	}
+
+	if err := services.ShareService.Consume(share, file.ID, visitor); err != nil {
+		respondShareError(c, err)
+		return
+	}
+
+	serveSharedFile(c, share, file)
+}
+
+// serveSharedFile 按分享的访问方式返回文件
+// 私有仓库的文件没有公开地址，跳转到短时效的签名地址
+func serveSharedFile(c *gin.Context, share *models.Share, file *models.File) {
+	if share.Mode == models.ShareModeProxy {
+		expires := ""
+		if share.ExpiresAt != nil {
+			expires = strconv.FormatInt(share.ExpiresAt.Unix(), 10)
+		}
+		serveFileContent(c, file, expires)
+		return
+	}
+
+	urls, repo := services.FileService.FileURLs(file)
+	target := urls[0]
+	if repo != nil && repo.Private {
+		target, _ = services.SignedURLService.BuildURL(file.ID, constants.ShareRedirectTTL*time.Second)
+	}
+
+	c.Header("Cache-Control", "no-store")
+	c.Redirect(http.StatusFound, target)
+}
+
+// respondShareError 将分享校验错误转换为对应的状态码
+func respondShareError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, services.ErrShareNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrShareRevoked), errors.Is(err, services.ErrShareExpired):
+		c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+	case errors.Is(err, services.ErrSharePasswordRequired), errors.Is(err, services.ErrSharePasswordInvalid):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error(), "password_required": true})
+	case errors.Is(err, services.ErrShareLimitReached):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		logger.Errorf("access share failed: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to access share"})
+	}
+}
+
+// sharedFileInfo 分享页面展示的文件信息
+func sharedFileInfo(file *models.File) gin.H {
+	return gin.H{
+		"filename": file.RawFilename,
+		"filesize": file.Filesize,
+		"mime":     file.Mime,
+		"width":    file.Width,
+		"height":   file.Height,
+	}
+}
+
+// sharePassword 从 password 查询参数或 X-Share-Password 请求头读取访问密码
+func sharePassword(c *gin.Context) string {
+	if password := c.Query("password"); password != "" {
+		return password
+	}
+	return c.GetHeader("X-Share-Password")
+}
+
+// shareVisitor 收集写入访问记录的访问者信息
+func shareVisitor(c *gin.Context) models.ShareAccessLog {
+	return models.ShareAccessLog{
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+		Referer:   c.Request.Referer(),
+	}
+}
+
+func sharePagination(c *gin.Context) (int, int) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+	return page, pageSize
+}
@@ -0,0 +1,107 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"pichub.api/constants"
+	"pichub.api/infra/database"
+	"pichub.api/infra/logger"
+	"pichub.api/models"
+	"pichub.api/routers/middleware"
+	"pichub.api/services"
+)
+
+// CreateSignedURL 为文件生成有时效的代理下载地址
+func CreateSignedURL(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUser(c)
+	fileID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || fileID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file ID"})
+		return
+	}
+
+	var req struct {
+		ExpiresIn int `json:"expires_in"` // 有效期，秒
+	}
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+	if req.ExpiresIn < 0 || req.ExpiresIn > constants.MaxSignedURLTTL {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("expires_in must be between 1 and %d", constants.MaxSignedURLTTL)})
+		return
+	}
+
+	if _, err := services.FileService.GetFile(userID, fileID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		return
+	}
+
+	ttl := services.SignedURLService.GetTTL(userID)
+	if req.ExpiresIn > 0 {
+		ttl = time.Duration(req.ExpiresIn) * time.Second
+	}
+
+	signedURL, expiresAt := services.SignedURLService.BuildURL(fileID, ttl)
+	c.JSON(http.StatusOK, gin.H{
+		"url":        signedURL,
+		"expires_at": expiresAt,
+	})
+}
+
+// ServeSignedFile 校验签名后使用文件所有者的 token 从GitHub读取并返回文件内容
+func ServeSignedFile(c *gin.Context) {
+	fileID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || fileID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid file ID"})
+		return
+	}
+
+	expires := c.Query("expires")
+	if err := services.SignedURLService.Verify(fileID, expires, c.Query("signature")); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+
+	var file models.File
+	if err := database.DB.First(&file, fileID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "File not found"})
+		return
+	}
+
+	serveFileContent(c, &file, expires)
+}
+
+// serveFileContent 以流的方式返回文件内容，浏览器缓存不超过签名有效期
+func serveFileContent(c *gin.Context, file *models.File, expires string) {
+	reader, err := services.FileService.OpenContent(file)
+	if err != nil {
+		logger.Errorf("open file %d failed: %v", file.ID, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to fetch file"})
+		return
+	}
+	defer reader.Close()
+
+	maxAge := int64(0)
+	if expiresAt, err := strconv.ParseInt(expires, 10, 64); err == nil {
+		maxAge = expiresAt - time.Now().Unix()
+	}
+
+	contentType := file.Mime
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	c.DataFromReader(http.StatusOK, -1, contentType, reader, map[string]string{
+		"Content-Disposition":    fmt.Sprintf("inline; filename*=UTF-8''%s", url.PathEscape(file.RawFilename)),
+		"Cache-Control":          fmt.Sprintf("private, max-age=%d", maxAge),
+		"X-Content-Type-Options": "nosniff",
+	})
+}
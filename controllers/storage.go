@@ -0,0 +1,109 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"pichub.api/models"
+	"pichub.api/pkg/validator"
+	"pichub.api/routers/middleware"
+	"pichub.api/services"
+)
+
+// GetUserUsage 获取当前用户的存储用量，按文件类型和仓库汇总
+func GetUserUsage(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUser(c)
+
+	usage, err := services.StorageService.GetUsage(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch usage"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"usage": usage})
+}
+
+// RecalculateUserUsage 根据文件记录重新统计当前用户的用量
+func RecalculateUserUsage(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUser(c)
+
+	if err := services.StorageService.Recalculate(userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to recalculate usage"})
+		return
+	}
+
+	usage, err := services.StorageService.GetUsage(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch usage"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Usage recalculated successfully",
+		"usage":   usage,
+	})
+}
+
+// ListStorageQuotas 获取配额，管理员可通过 user_id 查看其他用户
+func ListStorageQuotas(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUser(c)
+
+	if userIDStr := c.Query("user_id"); userIDStr != "" && middleware.IsAdmin(c) {
+		userID, _ = strconv.Atoi(userIDStr)
+	}
+
+	quotas, err := services.StorageService.ListQuotas(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch quotas"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"quotas": quotas})
+}
+
+// SetStorageQuota 设置用户或仓库配额，仅管理员可用
+func SetStorageQuota(c *gin.Context) {
+	if !middleware.IsAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin permission required"})
+		return
+	}
+
+	var req models.SetStorageQuotaRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": validator.TranslateErr(err)})
+		return
+	}
+
+	quota, err := services.StorageService.SetQuota(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Quota saved successfully",
+		"quota":   quota,
+	})
+}
+
+// DeleteStorageQuota 删除配额，仅管理员可用
+func DeleteStorageQuota(c *gin.Context) {
+	if !middleware.IsAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Admin permission required"})
+		return
+	}
+
+	quotaID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid quota ID"})
+		return
+	}
+
+	if err := services.StorageService.DeleteQuota(quotaID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete quota"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Quota deleted successfully"})
+}
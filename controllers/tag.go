@@ -0,0 +1,96 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"pichub.api/models"
+	"pichub.api/pkg/validator"
+	"pichub.api/routers/middleware"
+	"pichub.api/services"
+)
+
+// ListTags 获取用户的标签及各标签的文件数量
+func ListTags(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUser(c)
+
+	tags, err := services.TagService.Counts(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tags"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tags": tags})
+}
+
+// SuggestTags 按前缀推荐标签，用于输入时自动补全
+func SuggestTags(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUser(c)
+	limit, _ := strconv.Atoi(c.Query("limit"))
+
+	tags, err := services.TagService.Suggest(userID, c.Query("prefix"), limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tags"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tags": tags})
+}
+
+// TagFiles 为一个或多个文件添加标签
+func TagFiles(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUser(c)
+
+	var req models.TagFilesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": validator.TranslateErr(err)})
+		return
+	}
+
+	added, err := services.TagService.TagFiles(userID, req.FileIDs, req.Tags)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Files tagged successfully",
+		"added":   added,
+	})
+}
+
+// UntagFiles 移除一个或多个文件的标签
+func UntagFiles(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUser(c)
+
+	var req models.TagFilesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": validator.TranslateErr(err)})
+		return
+	}
+
+	if err := services.TagService.UntagFiles(userID, req.FileIDs, req.Tags); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Tags removed successfully"})
+}
+
+// DeleteTag 删除标签，文件本身不受影响
+func DeleteTag(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUser(c)
+	tagID, err := strconv.Atoi(c.Param("id"))
+	if err != nil || tagID <= 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tag ID"})
+		return
+	}
+
+	if err := services.TagService.DeleteTag(userID, tagID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Tag deleted successfully"})
+}
@@ -0,0 +1,111 @@
+package controllers
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"pichub.api/models"
+	"pichub.api/pkg/validator"
+	"pichub.api/routers/middleware"
+	"pichub.api/services"
+)
+
+// ListTrash 分页获取回收站中的文件
+func ListTrash(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUser(c)
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 10
+	}
+
+	files, total, err := services.TrashService.List(userID, page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch trash"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"files":          services.FileService.ToResponses(files),
+		"retention_days": services.TrashService.GetRetentionDays(userID),
+		"pagination": gin.H{
+			"current_page": page,
+			"page_size":    pageSize,
+			"total":        total,
+			"total_pages":  int(math.Ceil(float64(total) / float64(pageSize))),
+			"has_more":     page*pageSize < int(total),
+		},
+	})
+}
+
+// RestoreTrash 从回收站恢复文件
+func RestoreTrash(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUser(c)
+
+	var req models.TrashFilesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": validator.TranslateErr(err)})
+		return
+	}
+
+	restored, err := services.TrashService.Restore(userID, req.FileIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore files"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Files restored successfully",
+		"restored": restored,
+	})
+}
+
+// DeleteTrash 彻底删除回收站中的指定文件
+func DeleteTrash(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUser(c)
+
+	var req models.TrashFilesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": validator.TranslateErr(err)})
+		return
+	}
+
+	deleted, err := services.TrashService.Delete(userID, req.FileIDs)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   err.Error(),
+			"deleted": deleted,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Files deleted permanently",
+		"deleted": deleted,
+	})
+}
+
+// EmptyTrash 清空回收站
+func EmptyTrash(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUser(c)
+
+	deleted, err := services.TrashService.Empty(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   err.Error(),
+			"deleted": deleted,
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Trash emptied successfully",
+		"deleted": deleted,
+	})
+}
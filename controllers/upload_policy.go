@@ -0,0 +1,127 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"pichub.api/models"
+	"pichub.api/pkg/validator"
+	"pichub.api/routers/middleware"
+	"pichub.api/services"
+)
+
+// ListUploadPolicies 获取系统和当前用户的上传策略
+func ListUploadPolicies(c *gin.Context) {
+	userID, _ := middleware.GetCurrentUser(c)
+
+	policies, err := services.UploadPolicyService.ListPolicies(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch upload policies"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"policies": policies})
+}
+
+// CreateUploadPolicy 创建上传策略
+func CreateUploadPolicy(c *gin.Context) {
+	currentUserID, _ := middleware.GetCurrentUser(c)
+
+	var req models.UploadPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": validator.TranslateErr(err)})
+		return
+	}
+
+	// 验证权限
+	if req.UserID == 0 {
+		// 系统策略需要管理员权限
+		if !middleware.IsAdmin(c) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Admin permission required for system policy"})
+			return
+		}
+	} else if req.UserID != currentUserID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Can only add policy for yourself"})
+		return
+	}
+
+	policy, err := services.UploadPolicyService.CreatePolicy(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Upload policy created successfully",
+		"policy":  policy,
+	})
+}
+
+// UpdateUploadPolicy 更新上传策略
+func UpdateUploadPolicy(c *gin.Context) {
+	policy, ok := loadEditablePolicy(c)
+	if !ok {
+		return
+	}
+
+	var req models.UploadPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": validator.TranslateErr(err)})
+		return
+	}
+
+	if err := services.UploadPolicyService.UpdatePolicy(policy, req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Upload policy updated successfully",
+		"policy":  policy,
+	})
+}
+
+// DeleteUploadPolicy 删除上传策略
+func DeleteUploadPolicy(c *gin.Context) {
+	policy, ok := loadEditablePolicy(c)
+	if !ok {
+		return
+	}
+
+	if err := services.UploadPolicyService.DeletePolicy(policy); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete upload policy"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Upload policy deleted successfully"})
+}
+
+// loadEditablePolicy 读取路径中的策略，并检查当前用户是否有权修改
+func loadEditablePolicy(c *gin.Context) (*models.UploadPolicy, bool) {
+	currentUserID, _ := middleware.GetCurrentUser(c)
+
+	policyID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid policy ID"})
+		return nil, false
+	}
+
+	policy, err := services.UploadPolicyService.GetPolicy(policyID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Upload policy not found"})
+		return nil, false
+	}
+
+	if policy.UserID == 0 {
+		if !middleware.IsAdmin(c) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Admin permission required for system policy"})
+			return nil, false
+		}
+	} else if policy.UserID != currentUserID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Can only modify your own policy"})
+		return nil, false
+	}
+
+	return policy, true
+}
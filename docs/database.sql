@@ -125,3 +125,427 @@ INSERT INTO `pic_config` (`user_id`, `type`, `name`, `value`, `remark`)
 VALUES
     ('0', 'file', 'cdn_host', 'https://pics.mysticalpower.uk', '文件CDN域名');
 
+
+
+-- 图片占位图信息
+ALTER TABLE pic_files
+    ADD COLUMN blur_hash VARCHAR(64) NULL COMMENT 'BlurHash 占位字符串' AFTER filetype,
+    ADD COLUMN lqip TEXT NULL COMMENT '低质量占位图 base64 data URI' AFTER blur_hash,
+    ADD COLUMN dominant_color CHAR(7) NULL COMMENT '主色调 #rrggbb' AFTER lqip;
+
+-- 感知哈希（dHash），用于近似图片检测
+ALTER TABLE pic_files
+    ADD COLUMN phash BIGINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '感知哈希 dHash，0 表示未计算' AFTER dominant_color,
+    ADD INDEX `idx_user_phash` (`user_id`, `phash`);
+
+-- 水印配置示例，user_id 为 0 时作为系统默认配置
+-- INSERT INTO `pic_config` (`user_id`, `type`, `name`, `value`, `remark`)
+-- VALUES
+--     (1, 'watermark', 'enabled', 'true', '上传时自动添加水印'),
+--     (1, 'watermark', 'type', 'text', '水印类型 text/image'),
+--     (1, 'watermark', 'text', '© PicHub', '文字水印内容'),
+--     (1, 'watermark', 'file_id', '0', '图片水印引用的文件ID'),
+--     (1, 'watermark', 'position', 'bottom-right', '水印位置'),
+--     (1, 'watermark', 'opacity', '0.5', '不透明度'),
+--     (1, 'watermark', 'scale', '0.2', '水印宽度占图片宽度比例');
+
+-- 上传安全策略（系统配置）
+-- svg_policy: sanitize 清理脚本和外部引用后保存（默认）; reject 含活动内容时拒绝
+-- html_policy: reject 拒绝 HTML/XHTML 上传（默认）; text 强制按纯文本保存
+INSERT INTO `pic_config` (`user_id`, `type`, `name`, `value`, `remark`)
+VALUES
+    (0, 'security', 'svg_policy', 'sanitize', 'SVG 上传策略'),
+    (0, 'security', 'html_policy', 'reject', 'HTML 上传策略');
+
+CREATE TABLE pic_upload_policies (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL DEFAULT 0 COMMENT '用户ID，0为系统策略',
+    repo_id INT NOT NULL DEFAULT 0 COMMENT '仓库ID，0为对用户所有仓库生效',
+    allowed_types VARCHAR(50) NULL COMMENT '允许的文件类型代码，逗号分隔，空为不限制',
+    max_file_size INT UNSIGNED NOT NULL DEFAULT 0 COMMENT '单文件最大字节数，0为不限制',
+    max_width INT UNSIGNED NOT NULL DEFAULT 0 COMMENT '图片最大宽度，0为不限制',
+    max_height INT UNSIGNED NOT NULL DEFAULT 0 COMMENT '图片最大高度，0为不限制',
+    remark VARCHAR(50) NULL COMMENT '备注',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    index `idx_user_repo` (`user_id`, `repo_id`)
+)
+ENGINE=InnoDB
+DEFAULT CHARSET=utf8mb4
+COMMENT='上传策略表';
+
+CREATE TABLE pic_storage_usage (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL COMMENT '用户ID',
+    repo_id INT NOT NULL COMMENT '仓库ID',
+    filetype tinyint(1) UNSIGNED NOT NULL DEFAULT 0 COMMENT '文件类型，同 pic_files.filetype',
+    file_count BIGINT NOT NULL DEFAULT 0 COMMENT '文件数量',
+    total_size BIGINT NOT NULL DEFAULT 0 COMMENT '文件总大小，单位字节',
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    unique index `idx_user_repo_type` (`user_id`, `repo_id`, `filetype`)
+)
+ENGINE=InnoDB
+DEFAULT CHARSET=utf8mb4
+COMMENT='存储用量统计表';
+
+CREATE TABLE pic_storage_quotas (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL COMMENT '用户ID',
+    repo_id INT NOT NULL DEFAULT 0 COMMENT '仓库ID，0为用户总配额',
+    max_size BIGINT NOT NULL DEFAULT 0 COMMENT '最大容量，单位字节，0为不限制',
+    max_files BIGINT NOT NULL DEFAULT 0 COMMENT '最大文件数，0为不限制',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    unique index `idx_user_repo` (`user_id`, `repo_id`)
+)
+ENGINE=InnoDB
+DEFAULT CHARSET=utf8mb4
+COMMENT='存储配额表';
+
+CREATE TABLE pic_repo_pools (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL COMMENT '用户ID',
+    name VARCHAR(50) NOT NULL COMMENT '仓库池名称',
+    active_repo_id INT NOT NULL DEFAULT 0 COMMENT '当前写入的仓库ID',
+    threshold BIGINT NOT NULL COMMENT '单仓库容量阈值，单位字节',
+    measure VARCHAR(10) NOT NULL DEFAULT 'stored' COMMENT '容量统计方式: stored,文件表汇总; github,GitHub API',
+    auto_create tinyint(1) NOT NULL DEFAULT 0 COMMENT '没有可用仓库时是否自动创建',
+    name_template VARCHAR(80) NULL COMMENT '自动创建的仓库名模板，{n} 为序号',
+    private tinyint(1) NOT NULL DEFAULT 0 COMMENT '自动创建的仓库是否私有',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    index `idx_user_id` (`user_id`)
+)
+ENGINE=InnoDB
+DEFAULT CHARSET=utf8mb4
+COMMENT='仓库池表';
+
+ALTER TABLE pic_repositories
+    ADD COLUMN pool_id INT NOT NULL DEFAULT 0 COMMENT '所属仓库池ID，0为不属于任何仓库池' AFTER repo_branch;
+
+CREATE TABLE pic_repo_mirrors (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL COMMENT '用户ID',
+    repo_id INT NOT NULL COMMENT '主仓库ID',
+    type VARCHAR(20) NOT NULL COMMENT '镜像类型: github, webdav',
+    mirror_repo_id INT NOT NULL DEFAULT 0 COMMENT 'github 类型的镜像仓库ID',
+    endpoint VARCHAR(255) NULL COMMENT 'webdav 类型的写入地址',
+    username VARCHAR(100) NULL COMMENT 'webdav 用户名',
+    password VARCHAR(255) NULL COMMENT 'webdav 密码',
+    public_url VARCHAR(255) NULL COMMENT 'webdav 类型的公开访问地址前缀',
+    enabled tinyint(1) NOT NULL DEFAULT 1 COMMENT '是否启用',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    index `idx_repo_id` (`repo_id`)
+)
+ENGINE=InnoDB
+DEFAULT CHARSET=utf8mb4
+COMMENT='仓库镜像表';
+
+CREATE TABLE pic_file_replicas (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    file_id INT NOT NULL COMMENT '文件ID',
+    mirror_id INT NOT NULL COMMENT '镜像ID',
+    user_id INT NOT NULL COMMENT '用户ID',
+    path VARCHAR(255) NOT NULL COMMENT '镜像中的文件路径',
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' COMMENT '同步状态: pending, synced, failed, deleting',
+    attempts INT NOT NULL DEFAULT 0 COMMENT '尝试次数',
+    error VARCHAR(500) NULL COMMENT '最近一次失败原因',
+    synced_at TIMESTAMP NULL COMMENT '同步完成时间',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    unique index `idx_file_mirror` (`file_id`, `mirror_id`),
+    index `idx_mirror_status` (`mirror_id`, `status`)
+)
+ENGINE=InnoDB
+DEFAULT CHARSET=utf8mb4
+COMMENT='文件副本表';
+
+ALTER TABLE pic_files
+    ADD COLUMN storage_type VARCHAR(20) NOT NULL DEFAULT 'contents' COMMENT '存储位置: contents,仓库文件; release,release附件; lfs,Git LFS' AFTER phash,
+    ADD COLUMN storage_ref VARCHAR(100) NULL COMMENT 'release 附件ID 或 LFS 对象的 oid' AFTER storage_type,
+    ADD COLUMN remote_url VARCHAR(500) NULL COMMENT '不经过 CDN 的下载地址' AFTER storage_ref;
+
+-- 大文件配置示例，超过阈值（字节）的文件改用 release 附件或 LFS 存储
+-- INSERT INTO `pic_config` (`user_id`, `type`, `name`, `value`, `remark`)
+-- VALUES
+--     (0, 'file', 'large_file_threshold', '52428800', '大文件阈值'),
+--     (0, 'file', 'large_file_mode', 'release', '大文件存储方式 release/lfs');
+
+ALTER TABLE pic_repositories
+    ADD COLUMN url_template VARCHAR(255) NULL COMMENT '访问地址模板，预设名称(default/raw/jsdelivr/statically/custom)或包含占位符的模板' AFTER pool_id,
+    ADD COLUMN alt_url_templates VARCHAR(1000) NULL COMMENT '备用地址模板，多个用逗号分隔' AFTER url_template,
+    ADD COLUMN custom_domain VARCHAR(255) NULL COMMENT 'custom 预设使用的自定义域名' AFTER alt_url_templates;
+
+CREATE TABLE pic_purge_logs (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL COMMENT '用户ID',
+    file_id INT NOT NULL COMMENT '文件ID',
+    provider VARCHAR(20) NOT NULL COMMENT '刷新服务: jsdelivr, cloudflare, http',
+    url VARCHAR(500) NOT NULL COMMENT '刷新的地址',
+    reason VARCHAR(20) NULL COMMENT '触发原因: delete, replace, manual',
+    status VARCHAR(20) NOT NULL COMMENT '结果: success, failed',
+    status_code INT NOT NULL DEFAULT 0 COMMENT '响应状态码',
+    error VARCHAR(500) NULL COMMENT '失败原因',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    index `idx_user_file` (`user_id`, `file_id`)
+)
+ENGINE=InnoDB
+DEFAULT CHARSET=utf8mb4
+COMMENT='CDN 缓存刷新日志表';
+
+-- CDN 缓存刷新配置示例，user_id 为 0 时作为系统默认配置
+-- 刷新接口地址 (jsdelivr_endpoint、cloudflare_endpoint) 和 http_* 通用刷新配置只读取系统配置
+-- 系统配置对所有登录用户可见，不要在 user_id 为 0 的配置中保存密钥:
+-- 系统的 Cloudflare Token 使用环境变量 PURGE_CLOUDFLARE_TOKEN，通用刷新请求头使用 PURGE_HTTP_HEADERS
+-- INSERT INTO `pic_config` (`user_id`, `type`, `name`, `value`, `remark`)
+-- VALUES
+--     (1, 'purge', 'jsdelivr', 'true', '刷新 jsDelivr 缓存'),
+--     (1, 'purge', 'cloudflare_zone_id', 'xxxxxx', 'Cloudflare 区域ID'),
+--     (1, 'purge', 'cloudflare_token', 'xxxxxx', 'Cloudflare API Token'),
+--     (0, 'purge', 'http_template', 'https://cdn.example.com/purge?url={url}', '通用刷新地址模板'),
+--     (0, 'purge', 'http_method', 'POST', '通用刷新请求方法');
+
+CREATE TABLE pic_file_link_checks (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    file_id INT NOT NULL COMMENT '文件ID',
+    user_id INT NOT NULL COMMENT '用户ID',
+    url VARCHAR(500) NOT NULL COMMENT '检查的地址',
+    kind VARCHAR(20) NOT NULL COMMENT '地址类型: primary, alternate, raw',
+    status_code INT NOT NULL DEFAULT 0 COMMENT '响应状态码，请求失败时为0',
+    latency_ms BIGINT NOT NULL DEFAULT 0 COMMENT '响应耗时，毫秒',
+    broken tinyint(1) NOT NULL DEFAULT 0 COMMENT '是否失效',
+    error VARCHAR(500) NULL COMMENT '失败原因',
+    checked_at TIMESTAMP NULL COMMENT '最近检查时间',
+    unique index `idx_file_url` (`file_id`, `url`),
+    index `idx_user_broken` (`user_id`, `broken`)
+)
+ENGINE=InnoDB
+DEFAULT CHARSET=utf8mb4
+COMMENT='文件链接检查表';
+
+ALTER TABLE pic_repositories
+    ADD COLUMN private tinyint(1) NOT NULL DEFAULT 0 COMMENT '是否私有仓库，私有仓库的文件通过签名地址访问' AFTER pool_id;
+
+CREATE TABLE pic_shares (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL COMMENT '用户ID',
+    token VARCHAR(64) NOT NULL COMMENT '分享 token',
+    target_type VARCHAR(20) NOT NULL DEFAULT 'file' COMMENT '分享类型: file',
+    target_id INT NOT NULL COMMENT '分享对象ID',
+    mode VARCHAR(20) NOT NULL DEFAULT 'proxy' COMMENT '访问方式: proxy, redirect',
+    password_hash VARCHAR(255) NULL COMMENT '访问密码',
+    expires_at TIMESTAMP NULL COMMENT '过期时间，为空表示永久有效',
+    max_downloads INT NOT NULL DEFAULT 0 COMMENT '最大下载次数，0 为不限制',
+    download_count INT NOT NULL DEFAULT 0 COMMENT '已下载次数',
+    revoked_at TIMESTAMP NULL COMMENT '撤销时间',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    unique index `idx_token` (`token`),
+    index `idx_user_id` (`user_id`)
+)
+ENGINE=InnoDB
+DEFAULT CHARSET=utf8mb4
+COMMENT='分享链接表';
+
+CREATE TABLE pic_share_access_logs (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    share_id INT NOT NULL COMMENT '分享ID',
+    file_id INT NOT NULL DEFAULT 0 COMMENT '下载的文件ID，未通过校验时为0',
+    ip VARCHAR(64) NULL COMMENT '访问者IP',
+    user_agent VARCHAR(500) NULL COMMENT '访问者 User-Agent',
+    referer VARCHAR(500) NULL COMMENT '来源地址',
+    result VARCHAR(20) NOT NULL COMMENT '访问结果: ok, revoked, expired, password_invalid, limit_reached',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    index `idx_share_id` (`share_id`)
+)
+ENGINE=InnoDB
+DEFAULT CHARSET=utf8mb4
+COMMENT='分享访问记录表';
+
+CREATE TABLE pic_albums (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL COMMENT '用户ID',
+    parent_id INT NOT NULL DEFAULT 0 COMMENT '上级相册ID，0 为顶层相册',
+    name VARCHAR(100) NOT NULL COMMENT '相册名称',
+    description VARCHAR(500) NULL COMMENT '相册描述',
+    cover_file_id INT NOT NULL DEFAULT 0 COMMENT '封面文件ID，0 时使用相册中的第一个文件',
+    sort_order INT NOT NULL DEFAULT 0 COMMENT '排序',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    index `idx_user_parent` (`user_id`, `parent_id`)
+)
+ENGINE=InnoDB
+DEFAULT CHARSET=utf8mb4
+COMMENT='相册表';
+
+CREATE TABLE pic_album_files (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    album_id INT NOT NULL COMMENT '相册ID',
+    file_id INT NOT NULL COMMENT '文件ID',
+    sort_order INT NOT NULL DEFAULT 0 COMMENT '相册内排序',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    unique index `idx_album_file` (`album_id`, `file_id`),
+    index `idx_file_id` (`file_id`)
+)
+ENGINE=InnoDB
+DEFAULT CHARSET=utf8mb4
+COMMENT='相册文件关联表';
+
+ALTER TABLE pic_shares
+    MODIFY COLUMN target_type VARCHAR(20) NOT NULL DEFAULT 'file' COMMENT '分享类型: file, album';
+
+CREATE TABLE pic_tags (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL COMMENT '用户ID',
+    name VARCHAR(50) NOT NULL COMMENT '标签名',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    unique index `idx_user_name` (`user_id`, `name`)
+)
+ENGINE=InnoDB
+DEFAULT CHARSET=utf8mb4
+COMMENT='标签表';
+
+CREATE TABLE pic_file_tags (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    file_id INT NOT NULL COMMENT '文件ID',
+    tag_id INT NOT NULL COMMENT '标签ID',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    unique index `idx_file_tag` (`file_id`, `tag_id`),
+    index `idx_tag_id` (`tag_id`)
+)
+ENGINE=InnoDB
+DEFAULT CHARSET=utf8mb4
+COMMENT='文件标签关联表';
+
+ALTER TABLE pic_files
+    ADD COLUMN exif TEXT NULL COMMENT '上传时读取的 EXIF 文本字段，JSON 格式' AFTER remote_url;
+
+-- 全文索引使用 ngram 解析器以支持中文，已有文件通过 POST /api/v1/files/search/reindex 建立索引
+CREATE TABLE pic_file_search_index (
+    file_id INT NOT NULL PRIMARY KEY COMMENT '文件ID',
+    user_id INT NOT NULL COMMENT '用户ID',
+    filename VARCHAR(255) NULL COMMENT '原始文件名',
+    tags TEXT NULL COMMENT '标签，以空格分隔',
+    albums TEXT NULL COMMENT '所在相册名称，以空格分隔',
+    exif TEXT NULL COMMENT 'EXIF 字段值，以空格分隔',
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    index `idx_user_id` (`user_id`),
+    FULLTEXT INDEX `ft_search` (`filename`, `tags`, `albums`, `exif`) WITH PARSER ngram
+)
+ENGINE=InnoDB
+DEFAULT CHARSET=utf8mb4
+COMMENT='文件全文搜索索引表';
+
+ALTER TABLE pic_files
+    ADD COLUMN title VARCHAR(255) NULL COMMENT '标题' AFTER exif,
+    ADD COLUMN alt_text VARCHAR(500) NULL COMMENT '替代文本' AFTER title,
+    ADD COLUMN description TEXT NULL COMMENT '描述' AFTER alt_text,
+    ADD COLUMN metadata TEXT NULL COMMENT '用户自定义元数据，JSON 对象' AFTER description;
+
+-- 标题和描述加入全文索引，修改后通过 POST /api/v1/files/search/reindex 重建已有文件的索引
+ALTER TABLE pic_file_search_index
+    ADD COLUMN title VARCHAR(255) NULL COMMENT '标题' AFTER filename,
+    ADD COLUMN description TEXT NULL COMMENT '描述' AFTER title,
+    DROP INDEX `ft_search`,
+    ADD FULLTEXT INDEX `ft_search` (`filename`, `title`, `description`, `tags`, `albums`, `exif`) WITH PARSER ngram;
+
+-- 旧地址只能通过 /api/v1/r/{repo_id}/{path} 跳转，原来的 CDN 地址不会自动跳转
+CREATE TABLE pic_file_redirects (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL COMMENT '用户ID',
+    file_id INT NOT NULL COMMENT '文件ID',
+    repo_id INT NOT NULL COMMENT '移动前的仓库ID',
+    path VARCHAR(500) NOT NULL COMMENT '移动前的路径',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    unique index `idx_repo_path` (`repo_id`, `path`),
+    index `idx_file_id` (`file_id`)
+)
+ENGINE=InnoDB
+DEFAULT CHARSET=utf8mb4
+COMMENT='文件移动跳转表';
+
+-- 回收站，删除文件时只记录删除时间，保留期过后由定时任务彻底删除
+ALTER TABLE pic_files
+    ADD COLUMN deleted_at TIMESTAMP NULL COMMENT '移入回收站的时间' AFTER updated_at,
+    ADD INDEX `idx_deleted_at` (`deleted_at`);
+
+-- 回收站保留天数，默认 30 天，user_id 为 0 时作为全局默认值
+-- INSERT INTO `pic_config` (`user_id`, `type`, `name`, `value`, `remark`)
+-- VALUES (0, 'file', 'trash_retention_days', '30', '回收站保留天数');
+
+CREATE TABLE pic_bulk_jobs (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    user_id INT NOT NULL COMMENT '用户ID',
+    op VARCHAR(20) NOT NULL COMMENT '操作: delete, album, tag, move, visibility',
+    params TEXT NULL COMMENT '操作参数，JSON 格式',
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' COMMENT '状态: pending, running, completed, failed',
+    total INT NOT NULL DEFAULT 0 COMMENT '文件总数',
+    succeeded INT NOT NULL DEFAULT 0 COMMENT '成功数',
+    failed INT NOT NULL DEFAULT 0 COMMENT '失败数',
+    skipped INT NOT NULL DEFAULT 0 COMMENT '跳过数',
+    error VARCHAR(500) NULL COMMENT '任务中断的原因',
+    finished_at TIMESTAMP NULL COMMENT '结束时间',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    index `idx_user_id` (`user_id`),
+    index `idx_status` (`status`)
+)
+ENGINE=InnoDB
+DEFAULT CHARSET=utf8mb4
+COMMENT='批量操作任务表';
+
+CREATE TABLE pic_bulk_job_items (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    job_id INT NOT NULL COMMENT '任务ID',
+    file_id INT NOT NULL COMMENT '文件ID',
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' COMMENT '结果: pending, success, failed, skipped',
+    error VARCHAR(500) NULL COMMENT '失败原因',
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP ON UPDATE CURRENT_TIMESTAMP,
+    index `idx_job_status` (`job_id`, `status`)
+)
+ENGINE=InnoDB
+DEFAULT CHARSET=utf8mb4
+COMMENT='批量操作文件结果表';
+
+CREATE TABLE pic_file_versions (
+    id INT AUTO_INCREMENT PRIMARY KEY,
+    file_id INT NOT NULL COMMENT '文件ID',
+    user_id INT NOT NULL COMMENT '用户ID',
+    repo_id INT NOT NULL COMMENT 'blob 所在的仓库ID',
+    version INT NOT NULL COMMENT '版本号，同一文件内递增',
+    blob_sha VARCHAR(64) NOT NULL COMMENT '被替换前的 blob SHA',
+    hash_value VARCHAR(64) NULL COMMENT '文件散列值',
+    raw_filename VARCHAR(255) NULL COMMENT '原始文件名',
+    filesize INT UNSIGNED NOT NULL DEFAULT 0 COMMENT '文件大小',
+    width INT UNSIGNED NOT NULL DEFAULT 0 COMMENT '宽度',
+    height INT UNSIGNED NOT NULL DEFAULT 0 COMMENT '高度',
+    mime VARCHAR(100) NULL COMMENT 'MIME 类型',
+    filetype TINYINT UNSIGNED NOT NULL DEFAULT 0 COMMENT '文件类型',
+    reason VARCHAR(20) NOT NULL COMMENT '产生版本的操作: replace, rollback',
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    unique index `idx_file_version` (`file_id`, `version`),
+    index `idx_repo_id` (`repo_id`)
+)
+ENGINE=InnoDB
+DEFAULT CHARSET=utf8mb4
+COMMENT='文件历史版本表';
+
+-- 自定义链接模板，name 为模板名称，value 为模板内容
+-- 支持的占位符: {url}, {filename}, {width}, {height}, {alt}
+-- 占位符的值按模板格式转义: 含有 HTML 标签的模板做 HTML 转义，含有 Markdown 链接 "](" 的模板转义 \ [ ]
+-- 并把地址中的空格和括号编码为 %20、%28、%29，其他模板原样插入
+-- user_id 为 0 的模板对所有用户生效，用户的同名模板会覆盖它，与内置格式同名时覆盖内置格式
+-- INSERT INTO `pic_config` (`user_id`, `type`, `name`, `value`, `remark`)
+-- VALUES
+--     (1, 'link_template', 'markdown_link', '[![{alt}]({url})]({url})', '可点击的 Markdown 图片'),
+--     (1, 'link_template', 'html_figure', '<figure><img src="{url}" alt="{alt}" width="{width}" height="{height}"><figcaption>{filename}</figcaption></figure>', 'HTML figure');
+
+-- webdav 镜像密码改为加密保存，密钥为 DATA_ENCRYPTION_KEY，未配置时使用 JWT_SECRET
+-- 已有的明文密码仍可读取，重新保存镜像设置后加密
+ALTER TABLE pic_repo_mirrors
+    MODIFY COLUMN password VARCHAR(512) NULL COMMENT 'webdav 密码，加密保存';
+
+-- 系统的 Cloudflare Token 和通用刷新请求头改为从环境变量 PURGE_CLOUDFLARE_TOKEN、PURGE_HTTP_HEADERS 读取
+-- 系统配置对所有登录用户可见，删除已保存在其中的密钥
+DELETE FROM pic_config WHERE user_id = 0 AND type = 'purge' AND name IN ('cloudflare_token', 'http_headers');
@@ -1,13 +1,22 @@
 package helpers
 
-import "gorm.io/gorm"
+import (
+	"strings"
 
+	"gorm.io/gorm"
+)
+
+// Search 按字段模糊匹配，field 必须是可信的列名，search 中的通配符会被转义
 func Search(search, field string) func(db *gorm.DB) *gorm.DB {
 	return func(db *gorm.DB) *gorm.DB {
 		if search != "" {
-			db = db.Where("%"+field+"% ? LIKE", "%"+search+"%")
-			//db = db.Or("description LIKE ?", "%"+search+"%")
+			db = db.Where(field+" LIKE ?", "%"+EscapeLike(search)+"%")
 		}
 		return db
 	}
 }
+
+// EscapeLike 转义 LIKE 模式中的通配符
+func EscapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
+}
@@ -35,6 +35,23 @@ func main() {
 		logger.Fatalf("database DbConnection error: %s", err)
 	}
 
+	// 服务重启会中断正在运行的批量任务
+	if err := services.BulkService.MarkInterrupted(); err != nil {
+		logger.Errorf("mark interrupted bulk jobs error: %s", err)
+	}
+
+	// 为全文搜索上线前的文件补建索引
+	go func() {
+		indexed, err := services.SearchService.Backfill()
+		if err != nil {
+			logger.Errorf("backfill search index error: %s", err)
+			return
+		}
+		if indexed > 0 {
+			logger.Infof("backfilled search index of %d files", indexed)
+		}
+	}()
+
 	// 初始化验证器翻译器
 	if err := validator.InitTrans(); err != nil {
 		panic(err)
@@ -43,6 +60,10 @@ func main() {
 	//later separate migration，不迁移，直接使用sql语句来操作表结构即可
 	// migrations.Migrate()
 
+	// 启动定时任务，router.Run 会一直阻塞，需要在它之前启动
+	services.SchedulerService.StartScheduler()
+	defer services.SchedulerService.StopScheduler()
+
 	// 设置路由
 	router := routers.SetupRoute()
 	router.Static("/static", "./static")
@@ -50,8 +71,4 @@ func main() {
 		logger.Fatalf("Failed to start HTTP server: %v", err)
 	}
 
-	// 启动定时任务
-	// services.SchedulerService.StartScheduler()
-	// defer services.SchedulerService.StopScheduler()
-
 }
@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// albums 表结构，ParentID 为 0 表示顶层相册
+type Album struct {
+	ID          int       `json:"id" gorm:"primaryKey"`
+	UserID      int       `json:"user_id" gorm:"not null"`
+	ParentID    int       `json:"parent_id" gorm:"not null;default:0"`
+	Name        string    `json:"name" gorm:"not null"`
+	Description string    `json:"description"`
+	CoverFileID int       `json:"cover_file_id" gorm:"not null;default:0"`
+	SortOrder   int       `json:"sort_order" gorm:"not null;default:0"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// album_files 表结构，文件与相册多对多关联
+type AlbumFile struct {
+	ID        int       `json:"id" gorm:"primaryKey"`
+	AlbumID   int       `json:"album_id" gorm:"not null"`
+	FileID    int       `json:"file_id" gorm:"not null"`
+	SortOrder int       `json:"sort_order" gorm:"not null;default:0"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// 其他结构体
+
+type AlbumRequest struct {
+	Name        string `json:"name" form:"name" label:"相册名称" binding:"required,max=100"`
+	Description string `json:"description" form:"description" label:"相册描述" binding:"max=500"`
+	ParentID    int    `json:"parent_id" form:"parent_id" label:"上级相册" binding:"min=0"`
+	CoverFileID int    `json:"cover_file_id" form:"cover_file_id" label:"封面图片" binding:"min=0"`
+	SortOrder   int    `json:"sort_order" form:"sort_order" label:"排序"`
+}
+
+type AlbumFilesRequest struct {
+	FileIDs []int `json:"file_ids" form:"file_ids" label:"文件列表" binding:"required,min=1"`
+}
+
+type AlbumOrderRequest struct {
+	IDs []int `json:"ids" form:"ids" label:"排序列表" binding:"required,min=1"`
+}
+
+type AlbumResponse struct {
+	Album
+	FileCount int64  `json:"file_count"`
+	CoverURL  string `json:"cover_url,omitempty"`
+}
@@ -0,0 +1,74 @@
+package models
+
+import "time"
+
+// 批量操作类型
+const (
+	BulkOpDelete     = "delete"     // 移入回收站
+	BulkOpAlbum      = "album"      // 加入相册
+	BulkOpTag        = "tag"        // 添加标签
+	BulkOpMove       = "move"       // 移动到另一个仓库
+	BulkOpVisibility = "visibility" // 移动到公开或私有仓库，可见性由仓库决定
+)
+
+// 批量任务状态
+const (
+	BulkJobPending   = "pending"
+	BulkJobRunning   = "running"
+	BulkJobCompleted = "completed" // 所有文件都已处理，部分文件可能失败
+	BulkJobFailed    = "failed"    // 任务中断，未处理的文件保持 pending
+)
+
+// 单个文件的处理结果
+const (
+	BulkItemPending = "pending"
+	BulkItemSuccess = "success"
+	BulkItemFailed  = "failed"
+	BulkItemSkipped = "skipped" // 文件已满足目标状态，无需处理
+)
+
+// bulk_jobs 表结构
+type BulkJob struct {
+	ID         int        `json:"id" gorm:"primaryKey"`
+	UserID     int        `json:"user_id" gorm:"not null"`
+	Op         string     `json:"op" gorm:"not null"`
+	Params     string     `json:"-"` // 操作参数，BulkFilesRequest 的 JSON
+	Status     string     `json:"status" gorm:"not null;default:pending"`
+	Total      int        `json:"total" gorm:"not null;default:0"`
+	Succeeded  int        `json:"succeeded" gorm:"not null;default:0"`
+	Failed     int        `json:"failed" gorm:"not null;default:0"`
+	Skipped    int        `json:"skipped" gorm:"not null;default:0"`
+	Error      string     `json:"error"`
+	FinishedAt *time.Time `json:"finished_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+// bulk_job_items 表结构，记录任务中每个文件的处理结果
+type BulkJobItem struct {
+	ID        int       `json:"id" gorm:"primaryKey"`
+	JobID     int       `json:"job_id" gorm:"not null"`
+	FileID    int       `json:"file_id" gorm:"not null"`
+	Status    string    `json:"status" gorm:"not null;default:pending"`
+	Error     string    `json:"error"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// 其他结构体
+
+// BulkFilesRequest 批量操作请求，不同操作使用不同的参数
+type BulkFilesRequest struct {
+	FileIDs      []int    `json:"file_ids" form:"file_ids" label:"文件列表" binding:"required,min=1"`
+	Op           string   `json:"op" form:"op" label:"操作" binding:"required,oneof=delete album tag move visibility"`
+	AlbumID      int      `json:"album_id,omitempty" form:"album_id" label:"相册" binding:"min=0"`                  // album 操作的目标相册
+	Tags         []string `json:"tags,omitempty" form:"tags" label:"标签" binding:"omitempty,dive,required,max=50"` // tag 操作添加的标签
+	RepoID       int      `json:"repo_id,omitempty" form:"repo_id" label:"目标仓库" binding:"min=0"`                  // move 和 visibility 操作的目标仓库
+	Private      *bool    `json:"private,omitempty" form:"private" label:"是否私有"`                                  // visibility 操作的目标可见性
+	KeepRedirect bool     `json:"keep_redirect,omitempty" form:"keep_redirect" label:"保留旧地址跳转"`                   // move 和 visibility 操作是否保留旧地址，目标为私有仓库时不可用
+}
+
+// BulkJobResponse 任务及各文件的处理结果
+type BulkJobResponse struct {
+	BulkJob
+	Items []BulkJobItem `json:"items"`
+}
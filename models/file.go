@@ -1,63 +1,289 @@
 package models
 
 import (
+	"encoding/json"
 	"fmt"
+	"html"
+	"regexp"
+	"strings"
 	"time"
+
+	"gorm.io/gorm"
+)
+
+// 文件的存储位置
+const (
+	FileStorageContents = "contents" // 通过 Contents API 提交到仓库
+	FileStorageRelease  = "release"  // 作为 release 附件上传
+	FileStorageLFS      = "lfs"      // 通过 Git LFS 上传，仓库中只保存指针文件
 )
 
 // file 表结构
 type File struct {
-	ID          int        `json:"id" gorm:"primaryKey"`
-	RepoID      int        `json:"repo_id" gorm:"not null"`
-	UserID      int        `json:"user_id" gorm:"not null"`
-	Filename    string     `json:"filename" gorm:"not null"`
-	URL         string     `json:"url" gorm:"not null"`
-	HashValue   string     `json:"hash_value"`
-	RepoName    string     `json:"repo_name"`
-	RawFilename string     `json:"raw_filename"`
-	Filesize    uint       `json:"filesize"`
-	Width       uint       `json:"width"`
-	Height      uint       `json:"height"`
-	Mime        string     `json:"mime"`
-	Filetype    uint8      `json:"filetype" gorm:"default:0"`
-	CreatedAt   time.Time  `json:"created_at"`
-	UpdatedAt   time.Time  `json:"updated_at"`
-	Repository  Repository `json:"-" gorm:"foreignKey:RepoID"`
-	User        User       `json:"-" gorm:"foreignKey:UserID"`
+	ID            int            `json:"id" gorm:"primaryKey"`
+	RepoID        int            `json:"repo_id" gorm:"not null"`
+	UserID        int            `json:"user_id" gorm:"not null"`
+	Filename      string         `json:"filename" gorm:"not null"`
+	URL           string         `json:"url" gorm:"not null"`
+	HashValue     string         `json:"hash_value"`
+	RepoName      string         `json:"repo_name"`
+	RawFilename   string         `json:"raw_filename"`
+	Filesize      uint           `json:"filesize"`
+	Width         uint           `json:"width"`
+	Height        uint           `json:"height"`
+	Mime          string         `json:"mime"`
+	Filetype      uint8          `json:"filetype" gorm:"default:0"`
+	BlurHash      string         `json:"blur_hash"`
+	Lqip          string         `json:"lqip"`
+	DominantColor string         `json:"dominant_color"`
+	Phash         uint64         `json:"phash" gorm:"column:phash;default:0"`
+	StorageType   string         `json:"storage_type" gorm:"not null;default:contents"`
+	StorageRef    string         `json:"-"`          // release 附件ID 或 LFS 对象的 oid
+	RemoteURL     string         `json:"remote_url"` // 不经过 CDN 的下载地址，release 和 lfs 文件使用
+	Exif          string         `json:"-"`          // 上传时读取的 EXIF 文本字段，JSON 格式
+	Title         string         `json:"title"`
+	AltText       string         `json:"alt_text"`
+	Description   string         `json:"description"`
+	Metadata      string         `json:"-"` // 用户自定义的 JSON 元数据
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+	DeletedAt     gorm.DeletedAt `json:"deleted_at" gorm:"index"` // 移入回收站的时间
+	Repository    Repository     `json:"-" gorm:"foreignKey:RepoID"`
+	User          User           `json:"-" gorm:"foreignKey:UserID"`
 }
 
+// 内置的引用代码格式
+const (
+	LinkFormatURL      = "url"
+	LinkFormatMarkdown = "markdown"
+	LinkFormatHTML     = "html"
+	LinkFormatBBCode   = "bbcode"
+	LinkFormatRST      = "rst"
+)
+
 // 其他结构体
 
 type FileResponse struct {
-	ID          int       `json:"id"`
-	Filename    string    `json:"filename"`
-	FullURL     string    `json:"full_url"`
-	URL         string    `json:"url"`
-	RawFilename string    `json:"raw_filename"`
-	Filesize    uint      `json:"filesize"`
-	Width       uint      `json:"width,omitempty"`
-	Height      uint      `json:"height,omitempty"`
-	Mime        string    `json:"mime"`
-	CreatedAt   time.Time `json:"created_at"`
+	ID            int               `json:"id"`
+	Filename      string            `json:"filename"`
+	FullURL       string            `json:"full_url"`
+	URL           string            `json:"url"`
+	RawFilename   string            `json:"raw_filename"`
+	Filesize      uint              `json:"filesize"`
+	Width         uint              `json:"width,omitempty"`
+	Height        uint              `json:"height,omitempty"`
+	Mime          string            `json:"mime"`
+	BlurHash      string            `json:"blur_hash,omitempty"`
+	Lqip          string            `json:"lqip,omitempty"`
+	DominantColor string            `json:"dominant_color,omitempty"`
+	Title         string            `json:"title"`
+	AltText       string            `json:"alt_text"`
+	Description   string            `json:"description"`
+	Metadata      json.RawMessage   `json:"metadata,omitempty"`
+	Markdown      string            `json:"markdown"`
+	HTML          string            `json:"html"`
+	BBCode        string            `json:"bbcode"`
+	RST           string            `json:"rst"`
+	Links         map[string]string `json:"links,omitempty"` // 用户自定义链接模板生成的引用代码，键为模板名称
+	Tags          []string          `json:"tags"`
+	Exif          map[string]string `json:"exif,omitempty"`
+	AltURLs       []string          `json:"alt_urls,omitempty"`       // 仓库备用地址模板生成的地址
+	FallbackURLs  []string          `json:"fallback_urls,omitempty"`  // 镜像上的备用地址
+	URLExpiresAt  *time.Time        `json:"url_expires_at,omitempty"` // 私有仓库的签名地址过期时间
+	DeletedAt     *time.Time        `json:"deleted_at,omitempty"`     // 回收站中的文件移入的时间
+	CreatedAt     time.Time         `json:"created_at"`
+}
+
+// UpdateFileMetadataRequest 更新文件元数据，未提供的字段保持不变
+type UpdateFileMetadataRequest struct {
+	Title       *string         `json:"title" label:"标题" binding:"omitempty,max=255"`
+	AltText     *string         `json:"alt_text" label:"替代文本" binding:"omitempty,max=500"`
+	Description *string         `json:"description" label:"描述" binding:"omitempty,max=5000"`
+	Metadata    json.RawMessage `json:"metadata" label:"自定义元数据"` // 传 null 清空
+}
+
+// TrashFilesRequest 恢复或彻底删除回收站中的文件
+type TrashFilesRequest struct {
+	FileIDs []int `json:"file_ids" form:"file_ids" label:"文件列表" binding:"required,min=1"`
+}
+
+// BuildSnippets 根据地址和元数据生成 Markdown、HTML、BBCode 和 reStructuredText 引用代码
+func (r *FileResponse) BuildSnippets() {
+	alt := r.linkAlt()
+
+	r.Markdown = fmt.Sprintf("![%s](%s", markdownEscaper.Replace(alt), markdownURL(r.FullURL))
+	if r.Title != "" {
+		r.Markdown += fmt.Sprintf(` "%s"`, strings.ReplaceAll(r.Title, `"`, `\"`))
+	}
+	r.Markdown += ")"
+
+	r.HTML = fmt.Sprintf(`<img src="%s" alt="%s"`, html.EscapeString(r.FullURL), html.EscapeString(alt))
+	if r.Title != "" {
+		r.HTML += fmt.Sprintf(` title="%s"`, html.EscapeString(r.Title))
+	}
+	if r.Width > 0 && r.Height > 0 {
+		r.HTML += fmt.Sprintf(` width="%d" height="%d"`, r.Width, r.Height)
+	}
+	r.HTML += ">"
+
+	// 地址中的空格会截断 BBCode 和 reStructuredText 的地址
+	escapedURL := strings.ReplaceAll(r.FullURL, " ", "%20")
+	r.BBCode = fmt.Sprintf("[img]%s[/img]", escapedURL)
+
+	// 选项值不能换行
+	r.RST = fmt.Sprintf(".. image:: %s\n   :alt: %s", escapedURL, strings.Join(strings.Fields(alt), " "))
+	if r.Width > 0 && r.Height > 0 {
+		r.RST += fmt.Sprintf("\n   :width: %d\n   :height: %d", r.Width, r.Height)
+	}
+}
+
+// RenderLink 用文件信息替换链接模板中的占位符
+// 支持 {url}、{filename}、{width}、{height}、{alt}，按模板的格式转义插入的值:
+// 含有 HTML 标签的模板对所有值做 HTML 转义，含有 Markdown 链接 "](" 的模板转义文本中的 \ [ ]，
+// 地址中的空格和括号编码为 %20、%28、%29，其他模板原样插入
+func (r *FileResponse) RenderLink(template string) string {
+	url, filename, alt := r.FullURL, r.RawFilename, r.linkAlt()
+	switch {
+	case htmlTagPattern.MatchString(template):
+		url, filename, alt = html.EscapeString(url), html.EscapeString(filename), html.EscapeString(alt)
+	case strings.Contains(template, "]("):
+		url, filename, alt = markdownURL(url), markdownEscaper.Replace(filename), markdownEscaper.Replace(alt)
+	}
+
+	return strings.NewReplacer(
+		"{url}", url,
+		"{filename}", filename,
+		"{width}", fmt.Sprint(r.Width),
+		"{height}", fmt.Sprint(r.Height),
+		"{alt}", alt,
+	).Replace(template)
+}
+
+// htmlTagPattern 判断链接模板是否为 HTML
+var htmlTagPattern = regexp.MustCompile(`</?[a-zA-Z][a-zA-Z0-9-]*[\s/>]`)
+
+// markdownEscaper 转义 Markdown 链接文本中的特殊字符
+var markdownEscaper = strings.NewReplacer(`\`, `\\`, "[", `\[`, "]", `\]`)
+
+// markdownURL 编码地址中会截断 Markdown 链接的字符
+func markdownURL(url string) string {
+	return strings.NewReplacer(" ", "%20", "(", "%28", ")", "%29").Replace(url)
+}
+
+// LinkFormats 返回所有可用的引用代码，自定义模板与内置格式同名时覆盖内置格式
+func (r *FileResponse) LinkFormats() map[string]string {
+	formats := map[string]string{
+		LinkFormatURL:      r.FullURL,
+		LinkFormatMarkdown: r.Markdown,
+		LinkFormatHTML:     r.HTML,
+		LinkFormatBBCode:   r.BBCode,
+		LinkFormatRST:      r.RST,
+	}
+	for name, link := range r.Links {
+		formats[name] = link
+	}
+	return formats
+}
+
+// linkAlt 引用代码使用的替代文本，未设置时依次使用标题和原始文件名
+func (r *FileResponse) linkAlt() string {
+	if r.AltText != "" {
+		return r.AltText
+	}
+	if r.Title != "" {
+		return r.Title
+	}
+	return r.RawFilename
+}
+
+// UploadOptions 上传选项
+type UploadOptions struct {
+	IsForce   bool   // 强制上传，跳过散列值去重
+	OnSimilar string // 存在近似图片时的处理方式: warn 提示, reuse 复用已有文件
+	Watermark *bool  // 是否添加水印，nil 时按用户水印配置决定
+}
+
+// SimilarFileResponse 近似图片查询结果
+type SimilarFileResponse struct {
+	FileResponse
+	Distance int `json:"distance"`
 }
 
 func (f *File) ToResponse(cdnHost string) FileResponse {
-	full_url := fmt.Sprintf("%s/%s/%s", cdnHost, f.RepoName, f.URL)
+	full_url := f.GetFileURL(cdnHost)
+
+	var exif map[string]string
+	if f.Exif != "" {
+		_ = json.Unmarshal([]byte(f.Exif), &exif)
+	}
+
+	var deletedAt *time.Time
+	if f.DeletedAt.Valid {
+		deletedAt = &f.DeletedAt.Time
+	}
+
+	var metadata json.RawMessage
+	if f.Metadata != "" {
+		metadata = json.RawMessage(f.Metadata)
+	}
 
 	return FileResponse{
-		ID:          f.ID,
-		Filename:    f.Filename,
-		FullURL:     full_url,
-		URL:         f.URL,
-		RawFilename: f.RawFilename,
-		Filesize:    f.Filesize,
-		Width:       f.Width,
-		Height:      f.Height,
-		Mime:        f.Mime,
-		CreatedAt:   f.CreatedAt,
+		ID:            f.ID,
+		Filename:      f.Filename,
+		FullURL:       full_url,
+		URL:           f.URL,
+		RawFilename:   f.RawFilename,
+		Filesize:      f.Filesize,
+		Width:         f.Width,
+		Height:        f.Height,
+		Mime:          f.Mime,
+		BlurHash:      f.BlurHash,
+		Lqip:          f.Lqip,
+		DominantColor: f.DominantColor,
+		Exif:          exif,
+		DeletedAt:     deletedAt,
+		Title:         f.Title,
+		AltText:       f.AltText,
+		Metadata:      metadata,
+		Description:   f.Description,
+		CreatedAt:     f.CreatedAt,
 	}
 }
 
 func (f *File) GetFileURL(cdnHost string) string {
+	// release 附件和 LFS 对象不能通过仓库文件的 CDN 访问
+	if f.RemoteURL != "" {
+		return f.RemoteURL
+	}
 	return fmt.Sprintf("%s/%s/%s", cdnHost, f.RepoName, f.URL)
 }
+
+// 文件列表的排序字段
+const (
+	FileSortDefault = ""     // 按ID降序，指定相册时按相册内顺序
+	FileSortDate    = "date" // 按上传时间
+	FileSortSize    = "size" // 按文件大小
+	FileSortName    = "name" // 按原始文件名
+)
+
+// FileFilter 文件列表的筛选条件，零值表示不筛选
+type FileFilter struct {
+	RepoID      int
+	AlbumID     int
+	Tags        []string
+	TagMode     string // 多个标签的匹配方式: and, or，默认 and
+	Keyword     string // 原始文件名包含的内容
+	Mime        string // MIME 类型，以 /* 结尾时按前缀匹配，如 image/*
+	Filetypes   []int
+	MinSize     uint
+	MaxSize     uint
+	MinWidth    uint
+	MaxWidth    uint
+	MinHeight   uint
+	MaxHeight   uint
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	Sort        string
+	Asc         bool
+	Cursor      string // 上一页返回的游标，不为空时忽略页码
+}
@@ -0,0 +1,21 @@
+package models
+
+import "time"
+
+// file_redirects 表结构，记录文件移动前的位置，旧地址通过 /api/v1/r/:repo_id/*path 跳转到文件当前的地址
+type FileRedirect struct {
+	ID        int       `json:"id" gorm:"primaryKey"`
+	UserID    int       `json:"user_id" gorm:"not null"`
+	FileID    int       `json:"file_id" gorm:"not null"`
+	RepoID    int       `json:"repo_id" gorm:"not null"` // 移动前的仓库
+	Path      string    `json:"path" gorm:"not null"`    // 移动前的路径
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// 其他结构体
+
+type MoveFileRequest struct {
+	Path         string `json:"path" form:"path" label:"目标路径" binding:"max=500"`
+	RepoID       int    `json:"repo_id" form:"repo_id" label:"目标仓库" binding:"min=0"`
+	KeepRedirect bool   `json:"keep_redirect" form:"keep_redirect" label:"保留旧地址跳转"` // 只对 /api/v1/r/ 跳转接口生效，目标为私有仓库时不可用
+}
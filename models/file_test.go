@@ -0,0 +1,51 @@
+package models
+
+import "testing"
+
+func TestRenderLinkEscapesByContext(t *testing.T) {
+	file := &FileResponse{
+		FullURL:     "https://cdn.example.com/a b(1).png?x=1&y=2",
+		RawFilename: `<b>"a"</b>.png`,
+		AltText:     `x" onerror="alert(1)`,
+		Width:       10,
+		Height:      20,
+	}
+
+	tests := []struct {
+		name     string
+		template string
+		want     string
+	}{
+		{
+			"html figure",
+			`<figure><img src="{url}" alt="{alt}" width="{width}" height="{height}"><figcaption>{filename}</figcaption></figure>`,
+			`<figure><img src="https://cdn.example.com/a b(1).png?x=1&amp;y=2" alt="x&#34; onerror=&#34;alert(1)" width="10" height="20"><figcaption>&lt;b&gt;&#34;a&#34;&lt;/b&gt;.png</figcaption></figure>`,
+		},
+		{
+			"markdown link",
+			`[![{alt}]({url})]({url})`,
+			`[![x" onerror="alert(1)](https://cdn.example.com/a%20b%281%29.png?x=1&y=2)](https://cdn.example.com/a%20b%281%29.png?x=1&y=2)`,
+		},
+		{
+			"plain",
+			`{url} {filename}`,
+			`https://cdn.example.com/a b(1).png?x=1&y=2 <b>"a"</b>.png`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := file.RenderLink(tt.template); got != tt.want {
+				t.Fatalf("RenderLink =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderLinkMarkdownText(t *testing.T) {
+	file := &FileResponse{FullURL: "https://cdn.example.com/a.png", AltText: `a [b] \c`}
+	want := `![a \[b\] \\c](https://cdn.example.com/a.png)`
+	if got := file.RenderLink("![{alt}]({url})"); got != want {
+		t.Fatalf("RenderLink = %s, want %s", got, want)
+	}
+}
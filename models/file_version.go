@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// 产生历史版本的操作
+const (
+	FileVersionReplace  = "replace"  // 替换文件内容
+	FileVersionRollback = "rollback" // 回滚到历史版本
+)
+
+// file_versions 表结构，文件内容被替换前的版本，内容通过仓库历史中的 blob 读取
+type FileVersion struct {
+	ID          int       `json:"id" gorm:"primaryKey"`
+	FileID      int       `json:"file_id" gorm:"not null"`
+	UserID      int       `json:"user_id" gorm:"not null"`
+	RepoID      int       `json:"repo_id" gorm:"not null"` // blob 所在的仓库，文件移动后仍从原仓库读取
+	Version     int       `json:"version" gorm:"not null"` // 同一文件内从 1 开始递增
+	BlobSHA     string    `json:"blob_sha" gorm:"column:blob_sha;not null"`
+	HashValue   string    `json:"hash_value"`
+	RawFilename string    `json:"raw_filename"`
+	Filesize    uint      `json:"filesize"`
+	Width       uint      `json:"width"`
+	Height      uint      `json:"height"`
+	Mime        string    `json:"mime"`
+	Filetype    uint8     `json:"filetype"`
+	Reason      string    `json:"reason"` // 被哪个操作替换: replace, rollback
+	CreatedAt   time.Time `json:"created_at"`
+}
@@ -0,0 +1,42 @@
+package models
+
+import "time"
+
+// 链接类型
+const (
+	LinkKindPrimary   = "primary"   // 仓库地址模板生成的主地址
+	LinkKindAlternate = "alternate" // 备用地址模板生成的地址
+	LinkKindRaw       = "raw"       // raw.githubusercontent.com 原始地址
+)
+
+// file_link_checks 表结构，记录文件每个访问地址最近一次的检查结果
+type FileLinkCheck struct {
+	ID         int       `json:"id" gorm:"primaryKey"`
+	FileID     int       `json:"file_id" gorm:"not null"`
+	UserID     int       `json:"user_id" gorm:"not null"`
+	URL        string    `json:"url" gorm:"not null"`
+	Kind       string    `json:"kind" gorm:"not null"`
+	StatusCode int       `json:"status_code"`
+	LatencyMs  int64     `json:"latency_ms"`
+	Broken     bool      `json:"broken" gorm:"not null;default:false"`
+	Error      string    `json:"error"`
+	CheckedAt  time.Time `json:"checked_at"`
+}
+
+// 其他结构体
+
+// LinkReportSummary 链接检查汇总
+type LinkReportSummary struct {
+	CheckedLinks  int64      `json:"checked_links"`
+	BrokenLinks   int64      `json:"broken_links"`
+	BrokenFiles   int64      `json:"broken_files"`
+	LastCheckedAt *time.Time `json:"last_checked_at"`
+}
+
+// BrokenLinkItem 失效链接及所属文件
+type BrokenLinkItem struct {
+	FileLinkCheck
+	Filename    string `json:"filename"`
+	RawFilename string `json:"raw_filename"`
+	RepoID      int    `json:"repo_id"`
+}
@@ -0,0 +1,66 @@
+package models
+
+import "time"
+
+// 镜像的存储后端
+const (
+	MirrorTypeGithub = "github" // 用户的另一个 GitHub 仓库
+	MirrorTypeWebdav = "webdav" // WebDAV 服务，通过 PUT/DELETE 写入
+)
+
+// 副本同步状态
+const (
+	ReplicaStatusPending  = "pending"  // 等待写入镜像
+	ReplicaStatusSynced   = "synced"   // 已写入镜像
+	ReplicaStatusFailed   = "failed"   // 写入失败，等待补偿任务重试
+	ReplicaStatusDeleting = "deleting" // 主文件已删除，等待从镜像删除
+)
+
+// repo_mirrors 表结构，一个仓库可以声明多个镜像
+type RepoMirror struct {
+	ID           int       `json:"id" gorm:"primaryKey"`
+	UserID       int       `json:"user_id" gorm:"not null"`
+	RepoID       int       `json:"repo_id" gorm:"not null"`
+	Type         string    `json:"type" gorm:"not null"`
+	MirrorRepoID int       `json:"mirror_repo_id" gorm:"not null;default:0"` // github 类型使用的仓库
+	Endpoint     string    `json:"endpoint"`                                 // webdav 类型的写入地址
+	Username     string    `json:"username"`
+	Password     string    `json:"-"`
+	PublicURL    string    `json:"public_url"` // webdav 类型的公开访问地址前缀
+	Enabled      bool      `json:"enabled" gorm:"not null;default:true"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// file_replicas 表结构，记录每个文件在每个镜像上的同步状态
+type FileReplica struct {
+	ID        int        `json:"id" gorm:"primaryKey"`
+	FileID    int        `json:"file_id" gorm:"not null"`
+	MirrorID  int        `json:"mirror_id" gorm:"not null"`
+	UserID    int        `json:"user_id" gorm:"not null"`
+	Path      string     `json:"path" gorm:"not null"` // 镜像中的文件路径，主文件删除后用于清理
+	Status    string     `json:"status" gorm:"not null;default:pending"`
+	Attempts  int        `json:"attempts" gorm:"not null;default:0"`
+	Error     string     `json:"error"`
+	SyncedAt  *time.Time `json:"synced_at"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+}
+
+// 其他结构体
+
+type RepoMirrorRequest struct {
+	Type         string `json:"type" form:"type" label:"镜像类型" binding:"required,oneof=github webdav"`
+	MirrorRepoID int    `json:"mirror_repo_id" form:"mirror_repo_id" label:"镜像仓库"`
+	Endpoint     string `json:"endpoint" form:"endpoint" label:"写入地址" binding:"omitempty,url"`
+	Username     string `json:"username" form:"username" label:"用户名"`
+	Password     string `json:"password" form:"password" label:"密码"`
+	PublicURL    string `json:"public_url" form:"public_url" label:"公开访问地址" binding:"omitempty,url"`
+	Enabled      *bool  `json:"enabled" form:"enabled" label:"是否启用"`
+}
+
+// MirrorStatusResponse 镜像的副本同步统计
+type MirrorStatusResponse struct {
+	RepoMirror
+	Counts map[string]int64 `json:"counts"`
+}
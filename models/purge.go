@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// CDN 缓存刷新的服务商
+const (
+	PurgeProviderJsdelivr   = "jsdelivr"
+	PurgeProviderCloudflare = "cloudflare"
+	PurgeProviderHTTP       = "http"
+)
+
+// 刷新结果
+const (
+	PurgeStatusSuccess = "success"
+	PurgeStatusFailed  = "failed"
+)
+
+// PurgeConfig CDN 缓存刷新配置，存储在 config 表 type=purge 下
+// 接口地址和 http_* 通用刷新配置只能由管理员在系统配置 (user_id=0) 中设置
+// 系统的 Cloudflare Token 和通用刷新请求头属于密钥，分别通过环境变量 PURGE_CLOUDFLARE_TOKEN、PURGE_HTTP_HEADERS 配置
+type PurgeConfig struct {
+	Jsdelivr           bool   `json:"jsdelivr"`            // 是否刷新 jsDelivr 缓存
+	JsdelivrEndpoint   string `json:"jsdelivr_endpoint"`   // jsDelivr 刷新地址，默认 https://purge.jsdelivr.net
+	CloudflareZoneID   string `json:"cloudflare_zone_id"`  // Cloudflare 区域ID，为空时不刷新
+	CloudflareToken    string `json:"-"`                   // 具有 Cache Purge 权限的 API Token
+	CloudflareEndpoint string `json:"cloudflare_endpoint"` // Cloudflare API 地址，默认 https://api.cloudflare.com/client/v4
+	HTTPTemplate       string `json:"http_template"`       // 通用刷新地址模板，{url} 为转义后的文件地址，{path} 为逐段转义后的仓库路径
+	HTTPMethod         string `json:"http_method"`         // 通用刷新的请求方法，默认 POST
+	HTTPHeaders        string `json:"-"`                   // 通用刷新的附加请求头，格式 Key: Value，多个用换行分隔
+}
+
+// purge_logs 表结构
+type PurgeLog struct {
+	ID         int       `json:"id" gorm:"primaryKey"`
+	UserID     int       `json:"user_id" gorm:"not null"`
+	FileID     int       `json:"file_id" gorm:"not null"`
+	Provider   string    `json:"provider" gorm:"not null"`
+	URL        string    `json:"url" gorm:"not null"`
+	Reason     string    `json:"reason"` // 触发原因: delete, replace, manual
+	Status     string    `json:"status" gorm:"not null"`
+	StatusCode int       `json:"status_code"`
+	Error      string    `json:"error"`
+	CreatedAt  time.Time `json:"created_at"`
+}
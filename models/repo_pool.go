@@ -0,0 +1,51 @@
+package models
+
+import "time"
+
+// 仓库池容量的统计方式
+const (
+	PoolMeasureStored = "stored" // 按文件表中记录的 filesize 汇总
+	PoolMeasureGithub = "github" // 按 GitHub API 返回的仓库大小
+)
+
+// repo_pools 表结构，池中的仓库通过 repositories.pool_id 关联
+type RepoPool struct {
+	ID           int       `json:"id" gorm:"primaryKey"`
+	UserID       int       `json:"user_id" gorm:"not null"`
+	Name         string    `json:"name" gorm:"not null"`
+	ActiveRepoID int       `json:"active_repo_id" gorm:"not null;default:0"`
+	Threshold    int64     `json:"threshold" gorm:"not null"`                 // 仓库容量阈值，单位字节
+	Measure      string    `json:"measure" gorm:"not null;default:stored"`    // 容量统计方式: stored, github
+	AutoCreate   bool      `json:"auto_create" gorm:"not null;default:false"` // 没有可用仓库时自动创建
+	NameTemplate string    `json:"name_template"`                             // 自动创建的仓库名，{n} 替换为序号
+	Private      bool      `json:"private" gorm:"not null;default:false"`     // 自动创建的仓库是否私有
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// 其他结构体
+
+type RepoPoolRequest struct {
+	Name         string `json:"name" form:"name" label:"仓库池名称" binding:"required,max=50"`
+	Threshold    int64  `json:"threshold" form:"threshold" label:"容量阈值" binding:"required,min=1"`
+	Measure      string `json:"measure" form:"measure" label:"统计方式" binding:"omitempty,oneof=stored github"`
+	AutoCreate   bool   `json:"auto_create" form:"auto_create" label:"自动创建仓库"`
+	NameTemplate string `json:"name_template" form:"name_template" label:"仓库名模板" binding:"max=80"`
+	Private      bool   `json:"private" form:"private" label:"私有仓库"`
+	RepoIDs      []int  `json:"repo_ids" form:"repo_ids" label:"仓库列表"`
+}
+
+type RepoPoolMemberRequest struct {
+	RepoID int `json:"repo_id" form:"repo_id" label:"仓库ID" binding:"required,min=1"`
+}
+
+type RepoPoolResponse struct {
+	RepoPool
+	Members []RepoPoolMember `json:"members"`
+}
+
+type RepoPoolMember struct {
+	RepositoryResponse
+	Size   int64 `json:"size"`
+	Active bool  `json:"active"`
+}
@@ -1,20 +1,45 @@
 package models
 
 import (
+	"fmt"
 	"strings"
 	"time"
 )
 
+// 文件访问地址的预设模板
+const (
+	URLPresetDefault    = "default"    // 系统 CDN 域名 + 仓库名 + 路径
+	URLPresetRaw        = "raw"        // raw.githubusercontent.com
+	URLPresetJsdelivr   = "jsdelivr"   // cdn.jsdelivr.net
+	URLPresetStatically = "statically" // cdn.statically.io
+	URLPresetCustom     = "custom"     // 仓库绑定的自定义域名
+)
+
+// URLPresets 预设名称对应的地址模板
+// 可用占位符: {owner} {repo} {branch} {path} {hash} {cdn_host} {domain}
+var URLPresets = map[string]string{
+	URLPresetDefault:    "{cdn_host}/{repo}/{path}",
+	URLPresetRaw:        "https://raw.githubusercontent.com/{owner}/{repo}/{branch}/{path}",
+	URLPresetJsdelivr:   "https://cdn.jsdelivr.net/gh/{owner}/{repo}@{branch}/{path}",
+	URLPresetStatically: "https://cdn.statically.io/gh/{owner}/{repo}/{branch}/{path}",
+	URLPresetCustom:     "{domain}/{path}",
+}
+
 // repository 表结构
 type Repository struct {
-	ID         int       `json:"id" gorm:"primaryKey"`
-	UserID     int       `json:"user_id" gorm:"not null"`
-	RepoName   string    `json:"repo_name" gorm:"not null"`
-	RepoURL    string    `json:"repo_url" gorm:"not null"`
-	RepoBranch string    `json:"repo_branch" gorm:"not null;default:master"`
-	CreatedAt  time.Time `json:"created_at"`
-	UpdatedAt  time.Time `json:"updated_at"`
-	User       User      `json:"user" gorm:"foreignKey:UserID"`
+	ID              int       `json:"id" gorm:"primaryKey"`
+	UserID          int       `json:"user_id" gorm:"not null"`
+	RepoName        string    `json:"repo_name" gorm:"not null"`
+	RepoURL         string    `json:"repo_url" gorm:"not null"`
+	RepoBranch      string    `json:"repo_branch" gorm:"not null;default:master"`
+	PoolID          int       `json:"pool_id" gorm:"not null;default:0"`
+	Private         bool      `json:"private" gorm:"not null;default:false"` // 私有仓库的文件只能通过签名地址访问
+	URLTemplate     string    `json:"url_template"`                          // 访问地址模板，预设名称或包含占位符的模板
+	AltURLTemplates string    `json:"alt_url_templates"`                     // 备用地址模板，多个用逗号分隔
+	CustomDomain    string    `json:"custom_domain"`                         // custom 预设使用的域名，如 https://img.example.com
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+	User            User      `json:"user" gorm:"foreignKey:UserID"`
 }
 
 func (r *Repository) GetRepositoryName() string {
@@ -25,6 +50,87 @@ func (r *Repository) GetRepositoryName() string {
 	return ""
 }
 
+func (r *Repository) ToResponse() RepositoryResponse {
+	return RepositoryResponse{
+		ID:              r.ID,
+		RepoName:        r.RepoName,
+		RepoURL:         r.RepoURL,
+		RepoBranch:      r.RepoBranch,
+		PoolID:          r.PoolID,
+		Private:         r.Private,
+		URLTemplate:     r.URLTemplate,
+		AltURLTemplates: r.AltTemplateList(),
+		CustomDomain:    r.CustomDomain,
+		CreatedAt:       r.CreatedAt,
+	}
+}
+
+// AltTemplateList 解析备用地址模板
+func (r *Repository) AltTemplateList() []string {
+	var templates []string
+	for _, item := range strings.Split(r.AltURLTemplates, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			templates = append(templates, item)
+		}
+	}
+	return templates
+}
+
+// FileURLs 按仓库的地址模板生成文件的主地址和备用地址
+// release 附件和 LFS 文件不在仓库中，直接使用下载地址
+func (r *Repository) FileURLs(f *File, cdnHost string) (string, []string) {
+	if f.RemoteURL != "" {
+		return f.RemoteURL, nil
+	}
+
+	primary := r.RenderURL(r.URLTemplate, f, cdnHost)
+	var alternates []string
+	for _, template := range r.AltTemplateList() {
+		if url := r.RenderURL(template, f, cdnHost); url != primary {
+			alternates = append(alternates, url)
+		}
+	}
+	return primary, alternates
+}
+
+// RenderURL 渲染地址模板，模板为空时使用默认预设
+func (r *Repository) RenderURL(template string, f *File, cdnHost string) string {
+	if template == "" {
+		template = URLPresetDefault
+	}
+	if preset, ok := URLPresets[template]; ok {
+		template = preset
+	}
+
+	parts := strings.Split(strings.TrimSuffix(r.RepoURL, "/"), "/")
+	owner := ""
+	if len(parts) >= 2 {
+		owner = parts[len(parts)-2]
+	}
+
+	replacer := strings.NewReplacer(
+		"{owner}", owner,
+		"{repo}", r.GetRepositoryName(),
+		"{branch}", r.RepoBranch,
+		"{path}", f.URL,
+		"{hash}", f.HashValue,
+		"{cdn_host}", strings.TrimSuffix(cdnHost, "/"),
+		"{domain}", strings.TrimSuffix(r.CustomDomain, "/"),
+	)
+	return replacer.Replace(template)
+}
+
+// ValidateURLTemplate 检查模板是否为预设名称或包含 {path}/{hash} 占位符
+func ValidateURLTemplate(template string) error {
+	if _, ok := URLPresets[template]; ok {
+		return nil
+	}
+	if !strings.Contains(template, "{path}") && !strings.Contains(template, "{hash}") {
+		return fmt.Errorf("url template %q must be a preset or contain {path}", template)
+	}
+	return nil
+}
+
 // 其他结构体
 
 type AddRepositoryRequest struct {
@@ -34,11 +140,16 @@ type AddRepositoryRequest struct {
 }
 
 type RepositoryResponse struct {
-	ID         int       `json:"id"`
-	RepoName   string    `json:"repo_name"`
-	RepoURL    string    `json:"repo_url"`
-	RepoBranch string    `json:"repo_branch"`
-	CreatedAt  time.Time `json:"created_at"`
+	ID              int       `json:"id"`
+	RepoName        string    `json:"repo_name"`
+	RepoURL         string    `json:"repo_url"`
+	RepoBranch      string    `json:"repo_branch"`
+	PoolID          int       `json:"pool_id,omitempty"`
+	Private         bool      `json:"private"`
+	URLTemplate     string    `json:"url_template"`
+	AltURLTemplates []string  `json:"alt_url_templates"`
+	CustomDomain    string    `json:"custom_domain,omitempty"`
+	CreatedAt       time.Time `json:"created_at"`
 }
 
 type UpdateRepositoryRequest struct {
@@ -46,3 +157,16 @@ type UpdateRepositoryRequest struct {
 	RepoURL    string `json:"repo_url" form:"repo_url" label:"仓库URL" binding:"required"`
 	RepoBranch string `json:"repo_branch" form:"repo_branch" label:"仓库分支" binding:"required"`
 }
+
+type CreateRepositoryRequest struct {
+	RepoName    string `json:"repo_name" form:"repo_name" label:"仓库名称" binding:"required"`
+	Org         string `json:"org" form:"org" label:"组织"`
+	Description string `json:"description" form:"description" label:"仓库描述"`
+	Private     bool   `json:"private" form:"private" label:"是否私有"`
+}
+
+type RepositoryURLTemplateRequest struct {
+	URLTemplate     string   `json:"url_template" form:"url_template" label:"访问地址模板" binding:"max=255"`
+	AltURLTemplates []string `json:"alt_url_templates" form:"alt_url_templates" label:"备用地址模板"`
+	CustomDomain    string   `json:"custom_domain" form:"custom_domain" label:"自定义域名" binding:"omitempty,url"`
+}
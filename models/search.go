@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"pichub.api/config"
+)
+
+// file_search_index 表结构，每个文件一行，各字段建立 FULLTEXT 索引
+type FileSearchIndex struct {
+	FileID      int       `json:"file_id" gorm:"primaryKey;autoIncrement:false"`
+	UserID      int       `json:"user_id" gorm:"not null"`
+	Filename    string    `json:"filename"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Tags        string    `json:"tags"`   // 以空格分隔的标签名
+	Albums      string    `json:"albums"` // 以空格分隔的相册名
+	Exif        string    `json:"exif"`   // 以空格分隔的 EXIF 字段值
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+func (FileSearchIndex) TableName() string {
+	return config.Config.Database.Prefix + "file_search_index"
+}
+
+// 其他结构体
+
+type SearchHighlight struct {
+	Field   string `json:"field"`
+	Snippet string `json:"snippet"` // 匹配的内容以 <mark> 标记，其余内容已转义
+}
+
+type SearchResult struct {
+	FileResponse
+	Score      float64           `json:"score"`
+	Highlights []SearchHighlight `json:"highlights"`
+}
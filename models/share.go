@@ -0,0 +1,82 @@
+package models
+
+import "time"
+
+// 分享对象类型
+const (
+	ShareTargetFile  = "file"
+	ShareTargetAlbum = "album"
+)
+
+// 分享链接的访问方式
+const (
+	ShareModeProxy    = "proxy"    // 由服务端代理返回文件内容，默认方式
+	ShareModeRedirect = "redirect" // 跳转到文件地址，公开仓库的文件会暴露长期有效的地址，撤销分享后仍可访问
+)
+
+// 分享访问结果
+const (
+	ShareAccessOK              = "ok"
+	ShareAccessRevoked         = "revoked"
+	ShareAccessExpired         = "expired"
+	ShareAccessPasswordInvalid = "password_invalid"
+	ShareAccessLimitReached    = "limit_reached"
+)
+
+// shares 表结构
+type Share struct {
+	ID            int        `json:"id" gorm:"primaryKey"`
+	UserID        int        `json:"user_id" gorm:"not null"`
+	Token         string     `json:"token" gorm:"not null;uniqueIndex"`
+	TargetType    string     `json:"target_type" gorm:"not null"`
+	TargetID      int        `json:"target_id" gorm:"not null"`
+	Mode          string     `json:"mode" gorm:"not null;default:proxy"`
+	PasswordHash  string     `json:"-"`
+	ExpiresAt     *time.Time `json:"expires_at"`
+	MaxDownloads  int        `json:"max_downloads" gorm:"not null;default:0"` // 0 为不限制
+	DownloadCount int        `json:"download_count" gorm:"not null;default:0"`
+	RevokedAt     *time.Time `json:"revoked_at"`
+	CreatedAt     time.Time  `json:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+}
+
+// share_access_logs 表结构
+type ShareAccessLog struct {
+	ID        int       `json:"id" gorm:"primaryKey"`
+	ShareID   int       `json:"share_id" gorm:"not null"`
+	FileID    int       `json:"file_id"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	Referer   string    `json:"referer"`
+	Result    string    `json:"result" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// 其他结构体
+
+type CreateShareRequest struct {
+	TargetType   string `json:"target_type" form:"target_type" label:"分享类型" binding:"omitempty,oneof=file album"`
+	TargetID     int    `json:"target_id" form:"target_id" label:"分享对象" binding:"required,min=1"`
+	Mode         string `json:"mode" form:"mode" label:"访问方式" binding:"omitempty,oneof=redirect proxy"`
+	Password     string `json:"password" form:"password" label:"访问密码" binding:"max=64"`
+	ExpiresIn    int    `json:"expires_in" form:"expires_in" label:"有效期" binding:"min=0"` // 秒，0 为永久
+	MaxDownloads int    `json:"max_downloads" form:"max_downloads" label:"最大下载次数" binding:"min=0"`
+}
+
+type ShareResponse struct {
+	Share
+	URL              string `json:"url"`
+	PasswordRequired bool   `json:"password_required"`
+	Active           bool   `json:"active"`
+}
+
+// IsActive 分享是否仍可访问
+func (s *Share) IsActive() bool {
+	if s.RevokedAt != nil {
+		return false
+	}
+	if s.ExpiresAt != nil && time.Now().After(*s.ExpiresAt) {
+		return false
+	}
+	return s.MaxDownloads == 0 || s.DownloadCount < s.MaxDownloads
+}
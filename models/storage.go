@@ -0,0 +1,69 @@
+package models
+
+import (
+	"time"
+
+	"pichub.api/config"
+)
+
+// storage_usage 表结构，按用户、仓库、文件类型汇总的存储用量
+type StorageUsage struct {
+	ID        int       `json:"id" gorm:"primaryKey"`
+	UserID    int       `json:"user_id" gorm:"not null"`
+	RepoID    int       `json:"repo_id" gorm:"not null"`
+	Filetype  uint8     `json:"filetype" gorm:"not null;default:0"`
+	FileCount int64     `json:"file_count" gorm:"not null;default:0"`
+	TotalSize int64     `json:"total_size" gorm:"not null;default:0"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func (StorageUsage) TableName() string {
+	return config.Config.Database.Prefix + "storage_usage"
+}
+
+// storage_quotas 表结构，repo_id 为 0 表示用户总配额
+type StorageQuota struct {
+	ID        int       `json:"id" gorm:"primaryKey"`
+	UserID    int       `json:"user_id" gorm:"not null"`
+	RepoID    int       `json:"repo_id" gorm:"not null;default:0"`
+	MaxSize   int64     `json:"max_size" gorm:"not null;default:0"`  // 最大字节数，0 表示不限制
+	MaxFiles  int64     `json:"max_files" gorm:"not null;default:0"` // 最大文件数，0 表示不限制
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// 其他结构体
+
+type SetStorageQuotaRequest struct {
+	UserID   int   `json:"user_id" form:"user_id" label:"用户ID" binding:"required,min=1"`
+	RepoID   int   `json:"repo_id" form:"repo_id" label:"仓库ID" binding:"min=0"`
+	MaxSize  int64 `json:"max_size" form:"max_size" label:"最大容量" binding:"min=0"`
+	MaxFiles int64 `json:"max_files" form:"max_files" label:"最大文件数" binding:"min=0"`
+}
+
+// UsageStat 用量统计
+type UsageStat struct {
+	FileCount int64 `json:"file_count"`
+	TotalSize int64 `json:"total_size"`
+}
+
+type FiletypeUsage struct {
+	Filetype uint8  `json:"filetype"`
+	Name     string `json:"name"`
+	UsageStat
+}
+
+type RepositoryUsage struct {
+	RepoID   int           `json:"repo_id"`
+	RepoName string        `json:"repo_name"`
+	Quota    *StorageQuota `json:"quota,omitempty"`
+	UsageStat
+	SoftLimitPercent float64 `json:"soft_limit_percent"` // 占 GitHub 建议仓库大小的百分比
+}
+
+type UserUsageResponse struct {
+	Total        UsageStat         `json:"total"`
+	Quota        *StorageQuota     `json:"quota,omitempty"`
+	ByFiletype   []FiletypeUsage   `json:"by_filetype"`
+	Repositories []RepositoryUsage `json:"repositories"`
+}
@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// 标签筛选方式
+const (
+	TagMatchAll = "and" // 包含全部标签
+	TagMatchAny = "or"  // 包含任一标签
+)
+
+// tags 表结构，标签属于用户，同一用户下名称唯一
+type Tag struct {
+	ID        int       `json:"id" gorm:"primaryKey"`
+	UserID    int       `json:"user_id" gorm:"not null"`
+	Name      string    `json:"name" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// file_tags 表结构，文件与标签多对多关联
+type FileTag struct {
+	ID        int       `json:"id" gorm:"primaryKey"`
+	FileID    int       `json:"file_id" gorm:"not null"`
+	TagID     int       `json:"tag_id" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// 其他结构体
+
+type TagFilesRequest struct {
+	FileIDs []int    `json:"file_ids" form:"file_ids" label:"文件列表" binding:"required,min=1"`
+	Tags    []string `json:"tags" form:"tags" label:"标签" binding:"required,min=1,dive,required,max=50"`
+}
+
+type TagCount struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
+}
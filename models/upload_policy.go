@@ -0,0 +1,49 @@
+package models
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// upload_policies 表结构
+// user_id 为 0 表示系统策略，repo_id 为 0 表示对用户所有仓库生效
+type UploadPolicy struct {
+	ID           int       `json:"id" gorm:"primaryKey"`
+	UserID       int       `json:"user_id" gorm:"not null;default:0"`
+	RepoID       int       `json:"repo_id" gorm:"not null;default:0"`
+	AllowedTypes string    `json:"allowed_types"` // 允许的 filetype 代码，逗号分隔，空表示不限制
+	MaxFileSize  uint      `json:"max_file_size"` // 单文件最大字节数，0 表示不限制
+	MaxWidth     uint      `json:"max_width"`     // 图片最大宽度，0 表示不限制
+	MaxHeight    uint      `json:"max_height"`    // 图片最大高度，0 表示不限制
+	Remark       string    `json:"remark"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// AllowedTypeList 解析允许的文件类型代码
+func (p *UploadPolicy) AllowedTypeList() []uint8 {
+	var types []uint8
+	for _, item := range strings.Split(p.AllowedTypes, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		if code, err := strconv.Atoi(item); err == nil {
+			types = append(types, uint8(code))
+		}
+	}
+	return types
+}
+
+// 其他结构体
+
+type UploadPolicyRequest struct {
+	UserID       int     `json:"user_id" form:"user_id" label:"用户ID"`
+	RepoID       int     `json:"repo_id" form:"repo_id" label:"仓库ID"`
+	AllowedTypes []uint8 `json:"allowed_types" form:"allowed_types" label:"允许的文件类型" binding:"dive,max=5"`
+	MaxFileSize  uint    `json:"max_file_size" form:"max_file_size" label:"最大文件大小"`
+	MaxWidth     uint    `json:"max_width" form:"max_width" label:"最大宽度"`
+	MaxHeight    uint    `json:"max_height" form:"max_height" label:"最大高度"`
+	Remark       string  `json:"remark" form:"remark" label:"备注" binding:"max=50"`
+}
@@ -0,0 +1,16 @@
+package models
+
+// WatermarkConfig 水印配置，存储在 config 表 type=watermark 下
+type WatermarkConfig struct {
+	Enabled  bool    `json:"enabled"`  // 是否对所有上传的图片自动添加水印
+	Type     string  `json:"type"`     // 水印类型: text 文字, image 图片
+	Text     string  `json:"text"`     // 文字水印内容
+	Color    string  `json:"color"`    // 文字颜色，#rrggbb
+	FileID   int     `json:"file_id"`  // 图片水印，引用已上传到 PicHub 的文件
+	Position string  `json:"position"` // 位置: top-left, top-right, bottom-left, bottom-right, center
+	Opacity  float64 `json:"opacity"`  // 不透明度 0-1
+	Scale    float64 `json:"scale"`    // 水印宽度占图片宽度的比例 0-1
+	Margin   int     `json:"margin"`   // 距离边缘的像素
+
+	OwnerID int `json:"-"` // 配置所属用户，使用系统配置时为 0
+}
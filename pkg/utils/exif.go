@@ -0,0 +1,130 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+)
+
+// 读取的 EXIF 文本字段，IFD0 和 Exif 子 IFD 中的标签
+var exifTags = map[uint16]string{
+	0x010E: "ImageDescription",
+	0x010F: "Make",
+	0x0110: "Model",
+	0x0131: "Software",
+	0x0132: "DateTime",
+	0x013B: "Artist",
+	0x8298: "Copyright",
+	0x9003: "DateTimeOriginal",
+	0xA434: "LensModel",
+}
+
+const (
+	exifIFDPointer = 0x8769
+	exifTypeASCII  = 2
+	exifMaxEntries = 512
+)
+
+// ReadExif 读取 JPEG 中 EXIF 的常用文本字段，没有 EXIF 或格式不正确时返回空
+func ReadExif(content []byte) map[string]string {
+	tiff := findExifSegment(content)
+	if len(tiff) < 8 {
+		return nil
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return nil
+	}
+
+	result := map[string]string{}
+	exifOffset := readExifIFD(tiff, order, order.Uint32(tiff[4:8]), result)
+	if exifOffset > 0 {
+		readExifIFD(tiff, order, exifOffset, result)
+	}
+	if len(result) == 0 {
+		return nil
+	}
+	return result
+}
+
+// findExifSegment 在 JPEG 标记中查找 APP1 Exif 段，返回其中的 TIFF 数据
+func findExifSegment(content []byte) []byte {
+	if len(content) < 4 || content[0] != 0xFF || content[1] != 0xD8 {
+		return nil
+	}
+
+	for pos := 2; pos+4 <= len(content); {
+		if content[pos] != 0xFF {
+			return nil
+		}
+		marker := content[pos+1]
+		// 到达图像数据后不会再有 EXIF
+		if marker == 0xDA || marker == 0xD9 {
+			return nil
+		}
+		length := int(binary.BigEndian.Uint16(content[pos+2 : pos+4]))
+		end := pos + 2 + length
+		if length < 2 || end > len(content) {
+			return nil
+		}
+		segment := content[pos+4 : end]
+		if marker == 0xE1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return segment[6:]
+		}
+		pos = end
+	}
+	return nil
+}
+
+// readExifIFD 读取一个 IFD 中的文本字段，返回 Exif 子 IFD 的偏移
+func readExifIFD(tiff []byte, order binary.ByteOrder, offset uint32, result map[string]string) uint32 {
+	if int(offset)+2 > len(tiff) {
+		return 0
+	}
+	count := int(order.Uint16(tiff[offset:]))
+	if count > exifMaxEntries {
+		return 0
+	}
+
+	var exifOffset uint32
+	for i := 0; i < count; i++ {
+		entry := int(offset) + 2 + i*12
+		if entry+12 > len(tiff) {
+			break
+		}
+		tag := order.Uint16(tiff[entry:])
+		typ := order.Uint16(tiff[entry+2:])
+		n := order.Uint32(tiff[entry+4:])
+
+		if tag == exifIFDPointer {
+			exifOffset = order.Uint32(tiff[entry+8:])
+			continue
+		}
+
+		name, ok := exifTags[tag]
+		if !ok || typ != exifTypeASCII || n == 0 {
+			continue
+		}
+
+		// 不超过 4 字节的值直接存放在条目中
+		start := uint32(entry + 8)
+		if n > 4 {
+			start = order.Uint32(tiff[entry+8:])
+		}
+		if uint64(start)+uint64(n) > uint64(len(tiff)) {
+			continue
+		}
+
+		value := strings.TrimSpace(strings.TrimRight(string(tiff[start:start+n]), "\x00"))
+		if value != "" {
+			result[name] = value
+		}
+	}
+	return exifOffset
+}
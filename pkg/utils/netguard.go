@@ -0,0 +1,123 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ErrPrivateAddress 地址指向本机、内网或链路本地地址
+var ErrPrivateAddress = errors.New("address is not a public address")
+
+// sharedAddressSpace 运营商级 NAT 地址段 100.64.0.0/10
+var sharedAddressSpace = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// IsPublicIP 判断是否为公网地址，排除回环、私有、链路本地（含云服务元数据地址）、组播和未指定地址
+func IsPublicIP(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() {
+		return false
+	}
+	if ip4 := ip.To4(); ip4 != nil && (ip4[0] == 0 || sharedAddressSpace.Contains(ip4)) {
+		return false
+	}
+	return true
+}
+
+// CheckPublicURL 检查地址是否为 http(s) 且主机解析到公网地址，allowed 中的主机不做地址检查
+func CheckPublicURL(rawURL string, allowed []string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid url: %v", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("url scheme must be http or https")
+	}
+	host := u.Hostname()
+	if host == "" {
+		return fmt.Errorf("url host is required")
+	}
+	if hostAllowed(host, allowed) {
+		return nil
+	}
+
+	if _, err := resolvePublicIP(context.Background(), host); err != nil {
+		return err
+	}
+	return nil
+}
+
+// NewPublicHTTPClient 创建只能连接公网地址的 HTTP 客户端
+// 连接时检查解析后的地址并直接连接该地址，重定向和 DNS 重绑定也无法访问内网
+// allowed 返回不做检查的主机，在每次连接时读取
+func NewPublicHTTPClient(timeout time.Duration, allowed func() []string) *http.Client {
+	dialer := &net.Dialer{Timeout: 10 * time.Second, KeepAlive: 30 * time.Second}
+	transport := &http.Transport{
+		Proxy: nil,
+		DialContext: func(ctx context.Context, network, address string) (net.Conn, error) {
+			host, port, err := net.SplitHostPort(address)
+			if err != nil {
+				return nil, err
+			}
+			if allowed != nil && hostAllowed(host, allowed()) {
+				return dialer.DialContext(ctx, network, address)
+			}
+
+			ip, err := resolvePublicIP(ctx, host)
+			if err != nil {
+				return nil, err
+			}
+			return dialer.DialContext(ctx, network, net.JoinHostPort(ip.String(), port))
+		},
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: time.Second,
+	}
+	return &http.Client{Timeout: timeout, Transport: transport}
+}
+
+// resolvePublicIP 解析主机，任一地址不是公网地址时拒绝
+func resolvePublicIP(ctx context.Context, host string) (net.IP, error) {
+	var ips []net.IP
+	if ip := net.ParseIP(host); ip != nil {
+		ips = []net.IP{ip}
+	} else {
+		addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			return nil, err
+		}
+		for _, addr := range addrs {
+			ips = append(ips, addr.IP)
+		}
+	}
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("no address found for %s", host)
+	}
+
+	for _, ip := range ips {
+		if !IsPublicIP(ip) {
+			return nil, fmt.Errorf("%s: %w", host, ErrPrivateAddress)
+		}
+	}
+	return ips[0], nil
+}
+
+func hostAllowed(host string, allowed []string) bool {
+	for _, item := range allowed {
+		if item != "" && strings.EqualFold(strings.TrimSpace(item), host) {
+			return true
+		}
+	}
+	return false
+}
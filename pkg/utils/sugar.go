@@ -1,5 +1,10 @@
 package utils
 
+import (
+	"fmt"
+	"strconv"
+)
+
 //********************************************************
 // 语言语法糖 syntactic sugar
 //********************************************************
@@ -16,3 +21,66 @@ func If[T any](condition bool, trueVal, falseVal T) T {
 func IsEmpty(value interface{}) bool {
 	return value == "" || value == nil || value == 0 || value == false
 }
+
+// ToInt 将配置等来源的任意值转换为整数，无法转换时返回默认值
+func ToInt(value interface{}, defaultVal int) int {
+	switch v := value.(type) {
+	case int:
+		return v
+	case int64:
+		return int(v)
+	case float64:
+		return int(v)
+	case string:
+		if n, err := strconv.Atoi(v); err == nil {
+			return n
+		}
+	}
+	return defaultVal
+}
+
+// ToFloat 将配置等来源的任意值转换为浮点数，无法转换时返回默认值
+func ToFloat(value interface{}, defaultVal float64) float64 {
+	switch v := value.(type) {
+	case float64:
+		return v
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	case string:
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f
+		}
+	}
+	return defaultVal
+}
+
+// ToBool 将配置等来源的任意值转换为布尔值，无法转换时返回默认值
+func ToBool(value interface{}, defaultVal bool) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case float64:
+		return v != 0
+	case int:
+		return v != 0
+	case string:
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	}
+	return defaultVal
+}
+
+// ToString 将配置等来源的任意值转换为字符串
+func ToString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
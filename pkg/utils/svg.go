@@ -0,0 +1,347 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// SVG 中会执行脚本或嵌入外部文档的元素，整个元素连同子节点一起移除
+var svgBlockedElements = map[string]bool{
+	"script":        true,
+	"foreignobject": true,
+	"iframe":        true,
+	"embed":         true,
+	"object":        true,
+	"handler":       true,
+	"listener":      true,
+}
+
+// SVG 动画元素可以在运行时改写 href，需要检查 attributeName
+var svgAnimationElements = map[string]bool{
+	"set":              true,
+	"animate":          true,
+	"animatetransform": true,
+	"animatemotion":    true,
+}
+
+// 允许内联的 data URI 图片类型，svg+xml 可能再次携带脚本，不允许
+var svgAllowedDataURIs = []string{
+	"data:image/png",
+	"data:image/jpeg",
+	"data:image/jpg",
+	"data:image/gif",
+	"data:image/webp",
+}
+
+var cssURLPattern = regexp.MustCompile(`(?i)url\(\s*['"]?\s*([^'")\s]*)`)
+
+// svgSniffLength 按内容判断 SVG 时读取的最大长度，足够跳过较长的 XML 声明和注释
+const svgSniffLength = 8 << 10
+
+// IsSVG 根据扩展名、声明的类型或文件内容判断是否为 SVG
+// 按内容判断时只看文档的根元素，正文中内嵌 <svg> 的 Markdown、HTML、JS 等文件不算
+func IsSVG(content []byte, filename string, contentType string) bool {
+	if strings.ToLower(filepath.Ext(filename)) == ".svg" {
+		return true
+	}
+	if strings.Contains(strings.ToLower(contentType), "svg") {
+		return true
+	}
+	head := content
+	if len(head) > svgSniffLength {
+		head = head[:svgSniffLength]
+	}
+	return sniffSVGRoot(head)
+}
+
+// sniffSVGRoot 跳过 BOM、空白、XML 声明、注释和 DOCTYPE 后，判断第一个元素是否为 <svg>
+func sniffSVGRoot(head []byte) bool {
+	head = bytes.TrimPrefix(head, []byte("\xef\xbb\xbf"))
+	for {
+		head = bytes.TrimLeft(head, " \t\r\n")
+		lower := bytes.ToLower(head)
+
+		closer := ""
+		switch {
+		case bytes.HasPrefix(lower, []byte("<?")):
+			closer = "?>"
+		case bytes.HasPrefix(lower, []byte("<!--")):
+			closer = "-->"
+		case bytes.HasPrefix(lower, []byte("<!doctype")):
+			// DOCTYPE 可能带有 [...] 内部子集
+			closer = ">"
+			if open := bytes.IndexByte(head, '['); open >= 0 && open < bytes.IndexByte(head, '>') {
+				closer = "]>"
+			}
+		default:
+			for _, root := range []string{"<svg", "<svg:svg"} {
+				if bytes.HasPrefix(lower, []byte(root)) && len(lower) > len(root) &&
+					bytes.IndexByte([]byte(" \t\r\n/>"), lower[len(root)]) >= 0 {
+					return true
+				}
+			}
+			return false
+		}
+
+		// 未闭合的声明或注释，无法判断
+		end := bytes.Index(head, []byte(closer))
+		if end < 0 {
+			return false
+		}
+		head = head[end+len(closer):]
+	}
+}
+
+// IsHTML 根据扩展名、声明的类型或文件内容判断是否为 HTML/XHTML
+func IsHTML(content []byte, filename string, contentType string) bool {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".html", ".htm", ".xhtml", ".xht", ".shtml":
+		return true
+	}
+
+	contentType = strings.ToLower(contentType)
+	if strings.Contains(contentType, "text/html") || strings.Contains(contentType, "xhtml") {
+		return true
+	}
+
+	head := content
+	if len(head) > 1024 {
+		head = head[:1024]
+	}
+	head = bytes.ToLower(bytes.TrimSpace(head))
+	return bytes.HasPrefix(head, []byte("<!doctype html")) ||
+		bytes.HasPrefix(head, []byte("<html")) ||
+		bytes.Contains(head, []byte("<html xmlns"))
+}
+
+// SanitizeSVG 解析 SVG 并移除脚本、事件处理器和外部引用
+// 返回清理后的内容以及被移除内容的说明，无法解析时返回错误
+func SanitizeSVG(content []byte) ([]byte, []string, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(content))
+	decoder.Strict = true
+
+	var out bytes.Buffer
+	var removed []string
+	skipDepth := 0
+	hasSVGRoot := false
+	inStyle := false
+	var styleBuf bytes.Buffer
+	// RawToken 不检查标签是否配对，自行维护元素栈
+	var open []xml.Name
+
+	for {
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, removed, fmt.Errorf("invalid svg: %v", err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			open = append(open, t.Name)
+		case xml.EndElement:
+			if len(open) == 0 || open[len(open)-1] != t.Name {
+				return nil, removed, fmt.Errorf("invalid svg: unexpected end element </%s>", qualifiedName(t.Name))
+			}
+			open = open[:len(open)-1]
+		}
+
+		// 处于被移除元素内部时，只维护嵌套深度
+		if skipDepth > 0 {
+			switch token.(type) {
+			case xml.StartElement:
+				skipDepth++
+			case xml.EndElement:
+				skipDepth--
+			}
+			continue
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			name := strings.ToLower(t.Name.Local)
+			if name == "svg" {
+				hasSVGRoot = true
+			}
+
+			if svgBlockedElements[name] {
+				removed = append(removed, fmt.Sprintf("element <%s>", t.Name.Local))
+				skipDepth = 1
+				continue
+			}
+
+			if svgAnimationElements[name] && animatesLink(t) {
+				removed = append(removed, fmt.Sprintf("element <%s> animating a link", t.Name.Local))
+				skipDepth = 1
+				continue
+			}
+
+			attrs := make([]xml.Attr, 0, len(t.Attr))
+			for _, attr := range t.Attr {
+				if reason := unsafeSVGAttr(attr); reason != "" {
+					removed = append(removed, reason)
+					continue
+				}
+				attrs = append(attrs, attr)
+			}
+			t.Attr = attrs
+
+			if name == "style" {
+				// style 元素内容先缓存，结束时再检查是否包含外部引用
+				inStyle = true
+				styleBuf.Reset()
+			}
+			writeStartElement(&out, t)
+
+		case xml.EndElement:
+			if inStyle && strings.ToLower(t.Name.Local) == "style" {
+				css := styleBuf.String()
+				if reason := unsafeCSS(css); reason != "" {
+					removed = append(removed, "style element: "+reason)
+				} else {
+					xml.EscapeText(&out, []byte(css))
+				}
+				inStyle = false
+			}
+			out.WriteString("</" + qualifiedName(t.Name) + ">")
+
+		case xml.CharData:
+			if inStyle {
+				styleBuf.Write(t)
+				continue
+			}
+			xml.EscapeText(&out, t)
+
+		case xml.ProcInst:
+			// 只保留 xml 声明，其他处理指令（如 xml-stylesheet）可能引用外部资源
+			if t.Target == "xml" {
+				out.WriteString("<?xml " + string(t.Inst) + "?>")
+			} else {
+				removed = append(removed, fmt.Sprintf("processing instruction <?%s?>", t.Target))
+			}
+
+		case xml.Directive:
+			// DOCTYPE 可以定义实体，存在实体扩展和外部实体风险
+			removed = append(removed, "doctype declaration")
+
+		case xml.Comment:
+			// 注释直接丢弃
+		}
+	}
+
+	if len(open) > 0 {
+		return nil, removed, fmt.Errorf("invalid svg: unclosed element <%s>", qualifiedName(open[len(open)-1]))
+	}
+	if !hasSVGRoot {
+		return nil, removed, fmt.Errorf("invalid svg: missing <svg> root element")
+	}
+
+	return out.Bytes(), removed, nil
+}
+
+// unsafeSVGAttr 检查属性是否不安全，安全时返回空字符串
+func unsafeSVGAttr(attr xml.Attr) string {
+	name := strings.ToLower(attr.Name.Local)
+	value := strings.TrimSpace(strings.ToLower(attr.Value))
+	compact := strings.Join(strings.Fields(value), "")
+
+	if strings.HasPrefix(name, "on") {
+		return fmt.Sprintf("event handler attribute %s", qualifiedName(attr.Name))
+	}
+
+	if strings.Contains(compact, "javascript:") || strings.Contains(compact, "vbscript:") {
+		return fmt.Sprintf("script url in attribute %s", qualifiedName(attr.Name))
+	}
+
+	if name == "href" || name == "src" {
+		if strings.HasPrefix(value, "#") || value == "" {
+			return ""
+		}
+		for _, prefix := range svgAllowedDataURIs {
+			if strings.HasPrefix(compact, prefix) {
+				return ""
+			}
+		}
+		return fmt.Sprintf("external reference in attribute %s", qualifiedName(attr.Name))
+	}
+
+	if name == "style" {
+		if reason := unsafeCSS(attr.Value); reason != "" {
+			return "style attribute: " + reason
+		}
+	}
+
+	// fill="url(http://...)" 等表现属性同样可能引用外部资源
+	if strings.Contains(value, "url(") {
+		if reason := unsafeCSS(attr.Value); reason != "" {
+			return fmt.Sprintf("attribute %s: %s", qualifiedName(attr.Name), reason)
+		}
+	}
+
+	return ""
+}
+
+// unsafeCSS 检查 CSS 中的外部引用和脚本表达式
+func unsafeCSS(css string) string {
+	lower := strings.ToLower(css)
+	if strings.Contains(lower, "@import") {
+		return "@import rule"
+	}
+	if strings.Contains(lower, "expression(") {
+		return "css expression"
+	}
+	for _, match := range cssURLPattern.FindAllStringSubmatch(css, -1) {
+		target := strings.ToLower(match[1])
+		if strings.HasPrefix(target, "#") {
+			continue
+		}
+		allowed := false
+		for _, prefix := range svgAllowedDataURIs {
+			if strings.HasPrefix(target, prefix) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return "external url " + match[1]
+		}
+	}
+	return ""
+}
+
+// animatesLink 判断动画元素是否会修改 href
+func animatesLink(t xml.StartElement) bool {
+	for _, attr := range t.Attr {
+		if strings.ToLower(attr.Name.Local) == "attributename" {
+			target := strings.ToLower(attr.Value)
+			if target == "href" || strings.HasSuffix(target, ":href") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func writeStartElement(out *bytes.Buffer, t xml.StartElement) {
+	out.WriteString("<" + qualifiedName(t.Name))
+	for _, attr := range t.Attr {
+		out.WriteString(" " + qualifiedName(attr.Name) + `="`)
+		xml.EscapeText(out, []byte(attr.Value))
+		out.WriteString(`"`)
+	}
+	out.WriteString(">")
+}
+
+func qualifiedName(name xml.Name) string {
+	if name.Space == "" {
+		return name.Local
+	}
+	return name.Space + ":" + name.Local
+}
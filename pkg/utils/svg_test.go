@@ -0,0 +1,182 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestIsSVG(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		filename    string
+		contentType string
+		want        bool
+	}{
+		{"extension", "anything", "logo.SVG", "", true},
+		{"content type", "anything", "logo", "image/svg+xml", true},
+		{"bare root", `<svg xmlns="http://www.w3.org/2000/svg"></svg>`, "logo", "", true},
+		{"self closing root", `<svg/>`, "logo", "", true},
+		{"prolog comment doctype", "\xef\xbb\xbf<?xml version=\"1.0\"?>\n<!-- logo -->\n<!DOCTYPE svg PUBLIC \"-//W3C//DTD SVG 1.1//EN\" \"x.dtd\">\n<svg>", "logo", "", true},
+		{"doctype internal subset", `<!DOCTYPE svg [<!ENTITY a "b">]><svg>`, "logo", "", true},
+		{"prefixed root", `<svg:svg xmlns:svg="http://www.w3.org/2000/svg"/>`, "logo", "", true},
+		{"markdown with inline svg", "# Title\n\n<svg><script>alert(1)</script></svg>", "README.md", "text/markdown", false},
+		{"html with inline svg", "<!doctype html><html><body><svg></svg></body></html>", "page", "", false},
+		{"js with svg string", `const icon = "<svg></svg>";`, "icon.js", "application/javascript", false},
+		{"json with svg string", `{"icon": "<svg></svg>"}`, "icons.json", "application/json", false},
+		{"similar element name", `<svgfoo></svgfoo>`, "logo", "", false},
+		{"unterminated comment", `<!-- <svg>`, "logo", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := IsSVG([]byte(tt.content), tt.filename, tt.contentType); got != tt.want {
+				t.Fatalf("IsSVG = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSanitizeSVG(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		removed string   // 被移除内容说明中应包含的文字，为空表示不应移除任何内容
+		absent  []string // 清理后的内容中不应出现的片段
+		present []string // 清理后的内容中应保留的片段
+	}{
+		{
+			name:    "script element",
+			input:   `<svg><script>alert(1)</script><rect width="1"/></svg>`,
+			removed: "element <script>",
+			absent:  []string{"script", "alert"},
+			present: []string{`<rect width="1">`},
+		},
+		{
+			name:    "foreignObject",
+			input:   `<svg><foreignObject><iframe src="https://evil.example"></iframe></foreignObject></svg>`,
+			removed: "element <foreignObject>",
+			absent:  []string{"foreignObject", "iframe", "evil"},
+		},
+		{
+			name:    "event handler",
+			input:   `<svg onload="alert(1)"><circle r="1" onclick="steal()"/></svg>`,
+			removed: "event handler attribute onload",
+			absent:  []string{"onload", "onclick", "alert", "steal"},
+			present: []string{`<circle r="1">`},
+		},
+		{
+			name:    "external href",
+			input:   `<svg><image href="https://evil.example/a.png"/><use href="#local"/></svg>`,
+			removed: "external reference in attribute href",
+			absent:  []string{"evil.example"},
+			present: []string{`href="#local"`},
+		},
+		{
+			name:    "external xlink:href",
+			input:   `<svg xmlns:xlink="http://www.w3.org/1999/xlink"><a xlink:href="javascript:alert(1)"><text>x</text></a></svg>`,
+			removed: "script url in attribute xlink:href",
+			absent:  []string{"javascript"},
+		},
+		{
+			name:    "data uri image allowed",
+			input:   `<svg><image href="data:image/png;base64,AAAA"/></svg>`,
+			present: []string{`href="data:image/png;base64,AAAA"`},
+		},
+		{
+			name:    "svg data uri rejected",
+			input:   `<svg><image href="data:image/svg+xml;base64,AAAA"/></svg>`,
+			removed: "external reference",
+			absent:  []string{"svg+xml"},
+		},
+		{
+			name:    "css url in style attribute",
+			input:   `<svg><rect style="fill: url(https://evil.example/p)"/></svg>`,
+			removed: "style attribute: external url",
+			absent:  []string{"evil.example"},
+		},
+		{
+			name:    "css url in presentation attribute",
+			input:   `<svg><rect fill="url('http://evil.example/p')"/><rect fill="url(#grad)"/></svg>`,
+			removed: "attribute fill: external url",
+			absent:  []string{"evil.example"},
+			present: []string{`fill="url(#grad)"`},
+		},
+		{
+			name:    "css import in style element",
+			input:   `<svg><style>@import "https://evil.example/a.css";</style></svg>`,
+			removed: "style element: @import rule",
+			absent:  []string{"evil.example"},
+		},
+		{
+			name:    "animation rewriting href",
+			input:   `<svg><a><set attributeName="href" to="javascript:alert(1)"/></a></svg>`,
+			removed: "animating a link",
+			absent:  []string{"javascript"},
+		},
+		{
+			name:    "doctype with entities",
+			input:   `<!DOCTYPE svg [<!ENTITY xxe SYSTEM "file:///etc/passwd">]><svg><text>hi</text></svg>`,
+			removed: "doctype declaration",
+			absent:  []string{"ENTITY", "passwd"},
+		},
+		{
+			name:    "stylesheet processing instruction",
+			input:   `<?xml version="1.0"?><?xml-stylesheet href="https://evil.example/a.css"?><svg/>`,
+			removed: "processing instruction",
+			absent:  []string{"evil.example"},
+			present: []string{`<?xml version="1.0"?>`},
+		},
+		{
+			name:  "benign round trip",
+			input: `<?xml version="1.0"?><svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 10 10"><defs><linearGradient id="g"><stop offset="0" stop-color="#fff"/></linearGradient></defs><style>.a{fill:url(#g)}</style><rect class="a" width="10" height="10" fill="url(#g)"/><text x="1">a &amp; b</text></svg>`,
+			present: []string{
+				`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 10 10">`,
+				`<linearGradient id="g">`,
+				`<style>.a{fill:url(#g)}</style>`,
+				`<rect class="a" width="10" height="10" fill="url(#g)">`,
+				`<text x="1">a &amp; b</text>`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, removed, err := SanitizeSVG([]byte(tt.input))
+			if err != nil {
+				t.Fatalf("sanitize: %v", err)
+			}
+
+			reasons := strings.Join(removed, "; ")
+			if tt.removed == "" && len(removed) > 0 {
+				t.Fatalf("unexpected removals: %s", reasons)
+			}
+			if tt.removed != "" && !strings.Contains(reasons, tt.removed) {
+				t.Fatalf("removed = %q, want it to mention %q", reasons, tt.removed)
+			}
+			for _, s := range tt.absent {
+				if strings.Contains(string(out), s) {
+					t.Errorf("output still contains %q: %s", s, out)
+				}
+			}
+			for _, s := range tt.present {
+				if !strings.Contains(string(out), s) {
+					t.Errorf("output lost %q: %s", s, out)
+				}
+			}
+
+			// 清理后的内容应是合法的 SVG，再次清理不会再移除内容
+			if _, again, err := SanitizeSVG(out); err != nil || len(again) > 0 {
+				t.Errorf("sanitized output is not stable: err=%v removed=%v", err, again)
+			}
+		})
+	}
+}
+
+func TestSanitizeSVGRejectsInvalid(t *testing.T) {
+	for _, input := range []string{`<html></html>`, `<svg><rect></svg>`, `not xml`} {
+		if _, _, err := SanitizeSVG([]byte(input)); err == nil {
+			t.Errorf("expected error for %q", input)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"strconv"
+	"strings"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// 水印位置
+const (
+	WatermarkTopLeft     = "top-left"
+	WatermarkTopRight    = "top-right"
+	WatermarkBottomLeft  = "bottom-left"
+	WatermarkBottomRight = "bottom-right"
+	WatermarkCenter      = "center"
+)
+
+// RenderText 将文字渲染为透明背景的图片
+func RenderText(text string, c color.Color) *image.RGBA {
+	face := basicfont.Face7x13
+	width := font.MeasureString(face, text).Ceil()
+	height := face.Metrics().Height.Ceil()
+	if width == 0 {
+		width = 1
+	}
+
+	canvas := image.NewRGBA(image.Rect(0, 0, width, height))
+	drawer := &font.Drawer{
+		Dst:  canvas,
+		Src:  image.NewUniform(c),
+		Face: face,
+		Dot:  fixed.P(0, face.Metrics().Ascent.Ceil()),
+	}
+	drawer.DrawString(text)
+
+	return canvas
+}
+
+// ApplyWatermark 将水印图片按位置、透明度和缩放比例叠加到原图上
+// scale 为水印宽度占原图宽度的比例，margin 为距离边缘的像素
+func ApplyWatermark(img image.Image, mark image.Image, position string, opacity float64, scale float64, margin int) *image.RGBA {
+	bounds := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, bounds.Min, draw.Src)
+
+	// 按比例缩放水印
+	markW := int(math.Round(float64(bounds.Dx()) * scale))
+	if markW < 1 {
+		markW = 1
+	}
+	markH := int(math.Round(float64(mark.Bounds().Dy()) * float64(markW) / float64(mark.Bounds().Dx())))
+	if markH < 1 {
+		markH = 1
+	}
+	scaled := ResizeImageTo(mark, markW, markH)
+
+	// 计算水印位置
+	var x, y int
+	switch position {
+	case WatermarkTopLeft:
+		x, y = margin, margin
+	case WatermarkTopRight:
+		x, y = bounds.Dx()-markW-margin, margin
+	case WatermarkBottomLeft:
+		x, y = margin, bounds.Dy()-markH-margin
+	case WatermarkCenter:
+		x, y = (bounds.Dx()-markW)/2, (bounds.Dy()-markH)/2
+	default:
+		x, y = bounds.Dx()-markW-margin, bounds.Dy()-markH-margin
+	}
+
+	opacity = math.Max(0, math.Min(1, opacity))
+	mask := image.NewUniform(color.Alpha{A: uint8(opacity * 255)})
+	draw.DrawMask(dst, image.Rect(x, y, x+markW, y+markH), scaled, image.Point{}, mask, image.Point{}, draw.Over)
+
+	return dst
+}
+
+// EncodeImage 按原格式重新编码图片，目前支持 jpeg 和 png
+func EncodeImage(img image.Image, format string) ([]byte, error) {
+	var buf bytes.Buffer
+	switch format {
+	case "jpeg", "jpg":
+		if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90}); err != nil {
+			return nil, err
+		}
+	case "png":
+		if err := png.Encode(&buf, img); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported image format: %s", format)
+	}
+	return buf.Bytes(), nil
+}
+
+// ParseHexColor 解析 #rrggbb 格式的颜色，解析失败时返回默认颜色
+func ParseHexColor(hex string, defaultColor color.Color) color.Color {
+	hex = strings.TrimPrefix(hex, "#")
+	if len(hex) != 6 {
+		return defaultColor
+	}
+
+	value, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return defaultColor
+	}
+
+	return color.RGBA{R: uint8(value >> 16), G: uint8(value >> 8), B: uint8(value), A: 255}
+}
@@ -46,26 +46,113 @@ func RegisterRoutes(route *gin.Engine) {
 				user.POST("/github_token", controllers.UpdateGithubToken)
 				user.POST("/email", controllers.UpdateEmail)
 				user.POST("/email/verification", controllers.SendEmailVerification)
+				user.GET("/usage", controllers.GetUserUsage)
+				user.POST("/usage/recalculate", controllers.RecalculateUserUsage)
 			}
 
 			repo := protected.Group("/repositories")
 			{
 				logger.Infof("Registering repository routes\n")
 				repo.GET("", controllers.ListRepositories)
+				repo.GET("/url_presets", controllers.ListURLPresets)
 				repo.GET("/:id", controllers.GetRepository)
 				repo.POST("", controllers.AddRepository)
+				repo.POST("/create", controllers.CreateRepository)
 				repo.POST("/:id", controllers.UpdateRepository)
 				repo.POST("/:id/init", controllers.InitRepository)
 				repo.POST("/:id/delete", controllers.DeleteRepository)
+				repo.POST("/:id/url_templates", controllers.SetRepositoryURLTemplates)
+				repo.GET("/:id/mirrors", controllers.ListRepoMirrors)
+				repo.POST("/:id/mirrors", controllers.CreateRepoMirror)
+				repo.POST("/:id/mirrors/sync", controllers.SyncRepoMirrors)
+				repo.POST("/:id/mirrors/:mirror_id", controllers.UpdateRepoMirror)
+				repo.POST("/:id/mirrors/:mirror_id/delete", controllers.DeleteRepoMirror)
+			}
+
+			pools := protected.Group("/repo_pools")
+			{
+				pools.GET("", controllers.ListRepoPools)
+				pools.GET("/:id", controllers.GetRepoPool)
+				pools.POST("", controllers.CreateRepoPool)
+				pools.POST("/:id", controllers.UpdateRepoPool)
+				pools.POST("/:id/delete", controllers.DeleteRepoPool)
+				pools.POST("/:id/members", controllers.AddRepoPoolMember)
+				pools.POST("/:id/members/delete", controllers.RemoveRepoPoolMember)
 			}
 
 			// 在 protected 路由组中添加
 			files := protected.Group("/files")
 			{
 				files.GET("", controllers.ListFiles)
+				files.GET("/search", controllers.SearchFiles)
+				files.POST("/search/reindex", controllers.RebuildSearchIndex)
 				files.POST("/upload", controllers.UploadFile)
 				files.POST("/uploadStream/:repo_id", controllers.UploadStream)
 				files.POST("/delete", controllers.DeleteFile)
+				files.GET("/bulk", controllers.ListBulkJobs)
+				files.POST("/bulk", controllers.CreateBulkJob)
+				files.GET("/bulk/:id", controllers.GetBulkJob)
+				files.GET("/trash", controllers.ListTrash)
+				files.POST("/trash/restore", controllers.RestoreTrash)
+				files.POST("/trash/delete", controllers.DeleteTrash)
+				files.POST("/trash/empty", controllers.EmptyTrash)
+				files.GET("/:id/similar", controllers.FindSimilarFiles)
+				files.GET("/:id/links", controllers.GetFileLinks)
+				files.POST("/:id/metadata", controllers.UpdateFileMetadata)
+				files.POST("/:id/move", controllers.MoveFile)
+				files.POST("/:id/replace", controllers.ReplaceFile)
+				files.GET("/:id/versions", controllers.ListFileVersions)
+				files.POST("/:id/versions/:version_id/rollback", controllers.RollbackFile)
+				files.POST("/:id/purge", controllers.PurgeFile)
+				files.POST("/:id/signed_url", controllers.CreateSignedURL)
+				files.GET("/purge_logs", controllers.ListPurgeLogs)
+				files.GET("/link_report", controllers.GetLinkReport)
+				files.POST("/link_check", controllers.RunLinkCheck)
+			}
+
+			albums := protected.Group("/albums")
+			{
+				albums.GET("", controllers.ListAlbums)
+				albums.GET("/:id", controllers.GetAlbum)
+				albums.POST("", controllers.CreateAlbum)
+				albums.POST("/order", controllers.ReorderAlbums)
+				albums.POST("/:id", controllers.UpdateAlbum)
+				albums.POST("/:id/delete", controllers.DeleteAlbum)
+				albums.POST("/:id/files", controllers.AddAlbumFiles)
+				albums.POST("/:id/files/delete", controllers.RemoveAlbumFiles)
+				albums.POST("/:id/files/order", controllers.ReorderAlbumFiles)
+			}
+
+			tags := protected.Group("/tags")
+			{
+				tags.GET("", controllers.ListTags)
+				tags.GET("/suggest", controllers.SuggestTags)
+				tags.POST("/files", controllers.TagFiles)
+				tags.POST("/files/delete", controllers.UntagFiles)
+				tags.POST("/:id/delete", controllers.DeleteTag)
+			}
+
+			shares := protected.Group("/shares")
+			{
+				shares.GET("", controllers.ListShares)
+				shares.POST("", controllers.CreateShare)
+				shares.POST("/:id/revoke", controllers.RevokeShare)
+				shares.GET("/:id/logs", controllers.ListShareLogs)
+			}
+
+			policies := protected.Group("/upload_policies")
+			{
+				policies.GET("", controllers.ListUploadPolicies)
+				policies.POST("", controllers.CreateUploadPolicy)
+				policies.POST("/:id", controllers.UpdateUploadPolicy)
+				policies.POST("/:id/delete", controllers.DeleteUploadPolicy)
+			}
+
+			quotas := protected.Group("/quotas")
+			{
+				quotas.GET("", controllers.ListStorageQuotas)
+				quotas.POST("", controllers.SetStorageQuota)
+				quotas.POST("/:id/delete", controllers.DeleteStorageQuota)
 			}
 
 			config := protected.Group("/config")
@@ -80,5 +167,16 @@ func RegisterRoutes(route *gin.Engine) {
 		// 其他的公开路由
 		// 处理 github webhook 请求
 		v1.POST("/webhook/github", controllers.GithubWebhook)
+
+		// 签名地址代理下载，私有仓库的文件通过它访问
+		v1.GET("/f/:id", controllers.ServeSignedFile)
+
+		// 移动后的文件旧地址跳转
+		v1.GET("/r/:repo_id/*path", controllers.RedirectMovedFile)
+
+		// 分享链接
+		v1.GET("/s/:token", controllers.AccessShare)
+		v1.GET("/s/:token/info", controllers.GetShareInfo)
+		v1.GET("/s/:token/files/:file_id", controllers.AccessShareFile)
 	}
 }
@@ -0,0 +1,340 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+	"pichub.api/constants"
+	"pichub.api/infra/database"
+	"pichub.api/models"
+)
+
+type AlbumServiceImpl struct{}
+
+var AlbumService = &AlbumServiceImpl{}
+
+// ListAlbums 获取用户的相册，parentID 为 nil 时返回全部相册
+func (s *AlbumServiceImpl) ListAlbums(userID int, parentID *int) ([]models.AlbumResponse, error) {
+	var albums []models.Album
+	query := database.DB.Where("user_id = ?", userID)
+	if parentID != nil {
+		query = query.Where("parent_id = ?", *parentID)
+	}
+	if err := query.Order("sort_order ASC, id ASC").Find(&albums).Error; err != nil {
+		return nil, err
+	}
+	return s.toResponses(albums)
+}
+
+// GetAlbum 获取用户的相册
+func (s *AlbumServiceImpl) GetAlbum(userID int, albumID int) (*models.Album, error) {
+	var album models.Album
+	if err := database.DB.Where("id = ? AND user_id = ?", albumID, userID).First(&album).Error; err != nil {
+		return nil, errors.New("album not found")
+	}
+	return &album, nil
+}
+
+// GetAlbumDetail 获取相册详情，包括文件数量和封面地址
+func (s *AlbumServiceImpl) GetAlbumDetail(userID int, albumID int) (*models.AlbumResponse, error) {
+	album, err := s.GetAlbum(userID, albumID)
+	if err != nil {
+		return nil, err
+	}
+	responses, err := s.toResponses([]models.Album{*album})
+	if err != nil {
+		return nil, err
+	}
+	return &responses[0], nil
+}
+
+// CreateAlbum 创建相册
+func (s *AlbumServiceImpl) CreateAlbum(userID int, req models.AlbumRequest) (*models.Album, error) {
+	album := &models.Album{UserID: userID}
+	if err := s.fillAlbum(album, req); err != nil {
+		return nil, err
+	}
+	if err := database.DB.Create(album).Error; err != nil {
+		return nil, err
+	}
+	return album, nil
+}
+
+// UpdateAlbum 更新相册
+func (s *AlbumServiceImpl) UpdateAlbum(userID int, albumID int, req models.AlbumRequest) (*models.Album, error) {
+	album, err := s.GetAlbum(userID, albumID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.fillAlbum(album, req); err != nil {
+		return nil, err
+	}
+	if err := database.DB.Save(album).Error; err != nil {
+		return nil, err
+	}
+
+	// 相册名称参与全文索引
+	SearchService.RefreshAlbum(album.ID)
+	return album, nil
+}
+
+// DeleteAlbum 删除相册，只删除相册关系不删除文件，子相册移动到上级相册
+func (s *AlbumServiceImpl) DeleteAlbum(userID int, albumID int) error {
+	album, err := s.GetAlbum(userID, albumID)
+	if err != nil {
+		return err
+	}
+
+	var fileIDs []int
+	if err := database.DB.Model(&models.AlbumFile{}).Where("album_id = ?", album.ID).Pluck("file_id", &fileIDs).Error; err != nil {
+		return err
+	}
+
+	if err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Album{}).Where("parent_id = ? AND user_id = ?", album.ID, userID).
+			Update("parent_id", album.ParentID).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("album_id = ?", album.ID).Delete(&models.AlbumFile{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(album).Error
+	}); err != nil {
+		return err
+	}
+
+	SearchService.Refresh(fileIDs...)
+	return nil
+}
+
+// ReorderAlbums 按给定顺序设置相册的排序
+func (s *AlbumServiceImpl) ReorderAlbums(userID int, albumIDs []int) error {
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		for i, id := range albumIDs {
+			if err := tx.Model(&models.Album{}).Where("id = ? AND user_id = ?", id, userID).
+				Update("sort_order", i).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// AddFiles 将文件加入相册，已在相册中的文件忽略
+func (s *AlbumServiceImpl) AddFiles(userID int, albumID int, fileIDs []int) (int, error) {
+	if _, err := s.GetAlbum(userID, albumID); err != nil {
+		return 0, err
+	}
+
+	var ownedIDs []int
+	if err := database.DB.Model(&models.File{}).Where("id IN ? AND user_id = ?", fileIDs, userID).
+		Pluck("id", &ownedIDs).Error; err != nil {
+		return 0, err
+	}
+	if len(ownedIDs) == 0 {
+		return 0, errors.New("file not found")
+	}
+
+	var existing []int
+	if err := database.DB.Model(&models.AlbumFile{}).Where("album_id = ? AND file_id IN ?", albumID, ownedIDs).
+		Pluck("file_id", &existing).Error; err != nil {
+		return 0, err
+	}
+	skip := map[int]bool{}
+	for _, id := range existing {
+		skip[id] = true
+	}
+
+	// 新加入的文件排在相册末尾
+	var maxOrder int
+	if err := database.DB.Model(&models.AlbumFile{}).Where("album_id = ?", albumID).
+		Select("COALESCE(MAX(sort_order), -1)").Scan(&maxOrder).Error; err != nil {
+		return 0, err
+	}
+
+	var members []models.AlbumFile
+	for _, id := range ownedIDs {
+		if skip[id] {
+			continue
+		}
+		maxOrder++
+		members = append(members, models.AlbumFile{AlbumID: albumID, FileID: id, SortOrder: maxOrder})
+		skip[id] = true
+	}
+	if len(members) == 0 {
+		return 0, nil
+	}
+	if err := database.DB.Create(&members).Error; err != nil {
+		return 0, err
+	}
+
+	addedIDs := make([]int, 0, len(members))
+	for _, member := range members {
+		addedIDs = append(addedIDs, member.FileID)
+	}
+	SearchService.Refresh(addedIDs...)
+	return len(members), nil
+}
+
+// RemoveFiles 将文件移出相册，被移出的文件是封面时清除封面
+func (s *AlbumServiceImpl) RemoveFiles(userID int, albumID int, fileIDs []int) error {
+	album, err := s.GetAlbum(userID, albumID)
+	if err != nil {
+		return err
+	}
+
+	if err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("album_id = ? AND file_id IN ?", albumID, fileIDs).Delete(&models.AlbumFile{}).Error; err != nil {
+			return err
+		}
+		for _, id := range fileIDs {
+			if id == album.CoverFileID {
+				return tx.Model(album).Update("cover_file_id", 0).Error
+			}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	SearchService.Refresh(fileIDs...)
+	return nil
+}
+
+// ReorderFiles 按给定顺序设置相册中文件的排序
+func (s *AlbumServiceImpl) ReorderFiles(userID int, albumID int, fileIDs []int) error {
+	if _, err := s.GetAlbum(userID, albumID); err != nil {
+		return err
+	}
+
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		for i, id := range fileIDs {
+			if err := tx.Model(&models.AlbumFile{}).Where("album_id = ? AND file_id = ?", albumID, id).
+				Update("sort_order", i).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// RemoveFile 文件删除后清理相册关系和封面
+func (s *AlbumServiceImpl) RemoveFile(fileID int) error {
+	if err := database.DB.Where("file_id = ?", fileID).Delete(&models.AlbumFile{}).Error; err != nil {
+		return err
+	}
+	return database.DB.Model(&models.Album{}).Where("cover_file_id = ?", fileID).Update("cover_file_id", 0).Error
+}
+
+// fillAlbum 校验请求并填充相册字段
+func (s *AlbumServiceImpl) fillAlbum(album *models.Album, req models.AlbumRequest) error {
+	if req.ParentID != 0 {
+		if err := s.checkParent(album, req.ParentID); err != nil {
+			return err
+		}
+	}
+	if req.CoverFileID != 0 {
+		if _, err := FileService.GetFile(album.UserID, req.CoverFileID); err != nil {
+			return errors.New("cover file not found")
+		}
+	}
+
+	album.Name = req.Name
+	album.Description = req.Description
+	album.ParentID = req.ParentID
+	album.CoverFileID = req.CoverFileID
+	album.SortOrder = req.SortOrder
+	return nil
+}
+
+// checkParent 校验上级相册属于同一用户，且不会形成循环
+func (s *AlbumServiceImpl) checkParent(album *models.Album, parentID int) error {
+	for id, depth := parentID, 0; id != 0; depth++ {
+		if album.ID != 0 && id == album.ID {
+			return errors.New("album cannot be moved into itself or its sub album")
+		}
+		if depth >= constants.MaxAlbumDepth {
+			return fmt.Errorf("album nesting cannot exceed %d levels", constants.MaxAlbumDepth)
+		}
+
+		parent, err := s.GetAlbum(album.UserID, id)
+		if err != nil {
+			return errors.New("parent album not found")
+		}
+		id = parent.ParentID
+	}
+	return nil
+}
+
+// toResponses 构建相册响应，统计文件数量并生成封面地址
+// 未设置封面时使用相册中排在最前的文件
+func (s *AlbumServiceImpl) toResponses(albums []models.Album) ([]models.AlbumResponse, error) {
+	response := []models.AlbumResponse{}
+	if len(albums) == 0 {
+		return response, nil
+	}
+
+	albumIDs := make([]int, 0, len(albums))
+	for _, album := range albums {
+		albumIDs = append(albumIDs, album.ID)
+	}
+
+	var counts []struct {
+		AlbumID int
+		Total   int64
+	}
+	// 回收站中的文件不计入相册
+	if err := database.DB.Model(&models.AlbumFile{}).Select("album_id, COUNT(*) AS total").
+		Where("album_id IN ?", albumIDs).Where(s.activeFiles()).
+		Group("album_id").Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+	countMap := map[int]int64{}
+	for _, count := range counts {
+		countMap[count.AlbumID] = count.Total
+	}
+
+	coverIDs := map[int]int{}
+	var files []models.File
+	for _, album := range albums {
+		coverID := album.CoverFileID
+		if coverID == 0 && countMap[album.ID] > 0 {
+			var first models.AlbumFile
+			if err := database.DB.Where("album_id = ?", album.ID).Where(s.activeFiles()).
+				Order("sort_order ASC, id ASC").First(&first).Error; err == nil {
+				coverID = first.FileID
+			}
+		}
+		if coverID != 0 {
+			coverIDs[album.ID] = coverID
+		}
+	}
+	if len(coverIDs) > 0 {
+		ids := make([]int, 0, len(coverIDs))
+		for _, id := range coverIDs {
+			ids = append(ids, id)
+		}
+		if err := database.DB.Where("id IN ?", ids).Find(&files).Error; err != nil {
+			return nil, err
+		}
+	}
+	coverURLs := map[int]string{}
+	for _, item := range FileService.ToResponses(files) {
+		coverURLs[item.ID] = item.FullURL
+	}
+
+	for _, album := range albums {
+		response = append(response, models.AlbumResponse{
+			Album:     album,
+			FileCount: countMap[album.ID],
+			CoverURL:  coverURLs[coverIDs[album.ID]],
+		})
+	}
+	return response, nil
+}
+
+// activeFiles 限定相册中不在回收站的文件
+func (s *AlbumServiceImpl) activeFiles() *gorm.DB {
+	return database.DB.Where("file_id IN (?)", database.DB.Model(&models.File{}).Select("id"))
+}
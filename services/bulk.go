@@ -0,0 +1,319 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"pichub.api/constants"
+	"pichub.api/infra/database"
+	"pichub.api/infra/logger"
+	"pichub.api/models"
+)
+
+type BulkServiceImpl struct{}
+
+var BulkService = &BulkServiceImpl{}
+
+var ErrBulkJobNotFound = errors.New("bulk job not found")
+
+// 处理结果对应的任务计数字段
+var bulkCounterColumns = map[string]string{
+	models.BulkItemSuccess: "succeeded",
+	models.BulkItemFailed:  "failed",
+	models.BulkItemSkipped: "skipped",
+}
+
+// Create 校验参数并创建批量任务，任务在后台逐个处理文件
+func (s *BulkServiceImpl) Create(userID int, req models.BulkFilesRequest) (*models.BulkJob, error) {
+	fileIDs := s.uniqueIDs(req.FileIDs)
+	if len(fileIDs) == 0 {
+		return nil, errors.New("file_ids is required")
+	}
+	if len(fileIDs) > constants.MaxBulkFiles {
+		return nil, fmt.Errorf("at most %d files can be processed in one job", constants.MaxBulkFiles)
+	}
+
+	if err := s.prepare(userID, &req); err != nil {
+		return nil, err
+	}
+	params, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	job := models.BulkJob{
+		UserID: userID,
+		Op:     req.Op,
+		Params: string(params),
+		Status: models.BulkJobPending,
+		Total:  len(fileIDs),
+	}
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&job).Error; err != nil {
+			return err
+		}
+		items := make([]models.BulkJobItem, 0, len(fileIDs))
+		for _, id := range fileIDs {
+			items = append(items, models.BulkJobItem{JobID: job.ID, FileID: id, Status: models.BulkItemPending})
+		}
+		return tx.CreateInBatches(&items, constants.BulkItemBatch).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	go s.run(job, req)
+	return &job, nil
+}
+
+// List 分页获取用户的批量任务，最新的在前
+func (s *BulkServiceImpl) List(userID int, page int, pageSize int) ([]models.BulkJob, int64, error) {
+	var total int64
+	jobs := []models.BulkJob{}
+
+	query := database.DB.Model(&models.BulkJob{}).Where("user_id = ?", userID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	if err := query.Order("id DESC").Offset(offset).Limit(pageSize).Find(&jobs).Error; err != nil {
+		return nil, 0, err
+	}
+	return jobs, total, nil
+}
+
+// Get 获取任务状态及每个文件的处理结果
+func (s *BulkServiceImpl) Get(userID int, jobID int) (*models.BulkJobResponse, error) {
+	var job models.BulkJob
+	if err := database.DB.Where("id = ? AND user_id = ?", jobID, userID).First(&job).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrBulkJobNotFound
+		}
+		return nil, err
+	}
+
+	response := &models.BulkJobResponse{BulkJob: job, Items: []models.BulkJobItem{}}
+	if err := database.DB.Where("job_id = ?", job.ID).Order("id ASC").Find(&response.Items).Error; err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// MarkInterrupted 将服务重启前未完成的任务标记为失败，启动时调用
+func (s *BulkServiceImpl) MarkInterrupted() error {
+	now := time.Now()
+	return database.DB.Model(&models.BulkJob{}).
+		Where("status IN ?", []string{models.BulkJobPending, models.BulkJobRunning}).
+		Updates(map[string]interface{}{
+			"status":      models.BulkJobFailed,
+			"error":       "interrupted by server restart",
+			"finished_at": &now,
+		}).Error
+}
+
+// prepare 按操作类型校验参数，visibility 操作未指定仓库时选择第一个可见性相符的仓库
+func (s *BulkServiceImpl) prepare(userID int, req *models.BulkFilesRequest) error {
+	switch req.Op {
+	case models.BulkOpAlbum:
+		if req.AlbumID == 0 {
+			return errors.New("album_id is required")
+		}
+		if _, err := AlbumService.GetAlbum(userID, req.AlbumID); err != nil {
+			return err
+		}
+	case models.BulkOpTag:
+		req.Tags = TagService.NormalizeNames(req.Tags)
+		if len(req.Tags) == 0 {
+			return errors.New("tags are required")
+		}
+	case models.BulkOpMove:
+		if req.RepoID == 0 {
+			return errors.New("repo_id is required")
+		}
+		repo, err := RepositoryService.GetRepository(userID, req.RepoID)
+		if err != nil {
+			return errors.New("target repository not found")
+		}
+		if repo.Private && req.KeepRedirect {
+			return ErrRedirectToPrivateRepo
+		}
+	case models.BulkOpVisibility:
+		if req.Private == nil {
+			return errors.New("private is required")
+		}
+		// 旧地址跳转是公开的，不能指向私有仓库中的文件
+		if *req.Private && req.KeepRedirect {
+			return ErrRedirectToPrivateRepo
+		}
+		if req.RepoID > 0 {
+			repo, err := RepositoryService.GetRepository(userID, req.RepoID)
+			if err != nil {
+				return errors.New("target repository not found")
+			}
+			if repo.Private != *req.Private {
+				return errors.New("target repository visibility does not match")
+			}
+			return nil
+		}
+
+		repos, err := RepositoryService.ListRepositories(userID)
+		if err != nil {
+			return err
+		}
+		for _, repo := range repos {
+			if repo.Private == *req.Private {
+				req.RepoID = repo.ID
+				return nil
+			}
+		}
+		visibility := "public"
+		if *req.Private {
+			visibility = "private"
+		}
+		return fmt.Errorf("no %s repository found", visibility)
+	}
+	return nil
+}
+
+// run 逐个处理任务中的文件，单个文件失败不影响其他文件
+func (s *BulkServiceImpl) run(job models.BulkJob, req models.BulkFilesRequest) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Errorf("bulk job %d panicked: %v", job.ID, r)
+			s.finish(job.ID, fmt.Errorf("internal error"))
+		}
+	}()
+
+	if err := database.DB.Model(&models.BulkJob{}).Where("id = ?", job.ID).
+		Update("status", models.BulkJobRunning).Error; err != nil {
+		s.finish(job.ID, err)
+		return
+	}
+
+	lastID := 0
+	for {
+		var items []models.BulkJobItem
+		if err := database.DB.Where("job_id = ? AND status = ? AND id > ?", job.ID, models.BulkItemPending, lastID).
+			Order("id ASC").Limit(constants.BulkItemBatch).Find(&items).Error; err != nil {
+			s.finish(job.ID, err)
+			return
+		}
+		if len(items) == 0 {
+			break
+		}
+		lastID = items[len(items)-1].ID
+
+		for _, item := range items {
+			status, err := s.apply(job.UserID, req, item.FileID)
+			message := ""
+			if err != nil {
+				status = models.BulkItemFailed
+				message = err.Error()
+				if runes := []rune(message); len(runes) > constants.BulkErrorMaxLength {
+					message = string(runes[:constants.BulkErrorMaxLength])
+				}
+			}
+
+			if err := database.DB.Model(&item).Updates(map[string]interface{}{
+				"status": status,
+				"error":  message,
+			}).Error; err != nil {
+				s.finish(job.ID, err)
+				return
+			}
+			column := bulkCounterColumns[status]
+			if err := database.DB.Model(&models.BulkJob{}).Where("id = ?", job.ID).
+				Update(column, gorm.Expr(column+" + 1")).Error; err != nil {
+				s.finish(job.ID, err)
+				return
+			}
+		}
+	}
+
+	s.finish(job.ID, nil)
+}
+
+// apply 对单个文件执行操作，返回处理结果
+func (s *BulkServiceImpl) apply(userID int, req models.BulkFilesRequest, fileID int) (string, error) {
+	switch req.Op {
+	case models.BulkOpDelete:
+		if err := FileService.DeleteFile(fileID, userID); err != nil {
+			return "", err
+		}
+	case models.BulkOpAlbum:
+		added, err := AlbumService.AddFiles(userID, req.AlbumID, []int{fileID})
+		if err != nil {
+			return "", err
+		}
+		if added == 0 {
+			return models.BulkItemSkipped, nil
+		}
+	case models.BulkOpTag:
+		added, err := TagService.TagFiles(userID, []int{fileID}, req.Tags)
+		if err != nil {
+			return "", err
+		}
+		if added == 0 {
+			return models.BulkItemSkipped, nil
+		}
+	case models.BulkOpMove, models.BulkOpVisibility:
+		file, err := FileService.GetFile(userID, fileID)
+		if err != nil {
+			return "", errors.New("file not found")
+		}
+		if file.RepoID == req.RepoID {
+			return models.BulkItemSkipped, nil
+		}
+		if req.Op == models.BulkOpVisibility {
+			var repo models.Repository
+			if err := database.DB.First(&repo, file.RepoID).Error; err == nil && repo.Private == *req.Private {
+				return models.BulkItemSkipped, nil
+			}
+		}
+
+		if _, err := FileService.MoveFile(userID, fileID, models.MoveFileRequest{
+			RepoID:       req.RepoID,
+			KeepRedirect: req.KeepRedirect,
+		}); err != nil {
+			return "", err
+		}
+	default:
+		return "", fmt.Errorf("unsupported operation %s", req.Op)
+	}
+	return models.BulkItemSuccess, nil
+}
+
+// finish 记录任务结束，err 不为空时任务标记为失败
+func (s *BulkServiceImpl) finish(jobID int, err error) {
+	now := time.Now()
+	updates := map[string]interface{}{
+		"status":      models.BulkJobCompleted,
+		"finished_at": &now,
+	}
+	if err != nil {
+		logger.Errorf("bulk job %d failed: %v", jobID, err)
+		updates["status"] = models.BulkJobFailed
+		updates["error"] = err.Error()
+	}
+	if err := database.DB.Model(&models.BulkJob{}).Where("id = ?", jobID).Updates(updates).Error; err != nil {
+		logger.Errorf("update bulk job %d failed: %v", jobID, err)
+	}
+}
+
+// uniqueIDs 去除重复和无效的文件ID，保持原有顺序
+func (s *BulkServiceImpl) uniqueIDs(ids []int) []int {
+	seen := map[int]bool{}
+	result := make([]int, 0, len(ids))
+	for _, id := range ids {
+		if id <= 0 || seen[id] {
+			continue
+		}
+		seen[id] = true
+		result = append(result, id)
+	}
+	return result
+}
@@ -0,0 +1,125 @@
+package services
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"gorm.io/driver/mysql"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+	"pichub.api/infra/database"
+)
+
+// fakeDB 不依赖 MySQL 的测试数据库，按 SQL 片段和参数返回预设的结果
+type fakeDB struct {
+	mu      sync.Mutex
+	results []fakeResult
+	queries []string
+}
+
+// fakeResult 预设的查询结果，SQL 包含 match 且参数与 args 一致时返回
+type fakeResult struct {
+	match   string
+	args    []interface{}
+	columns []string
+	rows    [][]driver.Value
+}
+
+// newFakeDB 替换 database.DB，测试结束后恢复
+func newFakeDB(t *testing.T) *fakeDB {
+	f := &fakeDB{}
+	db, err := gorm.Open(mysql.New(mysql.Config{
+		Conn:                      sql.OpenDB(f),
+		SkipInitializeWithVersion: true,
+	}), &gorm.Config{
+		NamingStrategy: schema.NamingStrategy{TablePrefix: "pic_"},
+		Logger:         logger.Discard,
+	})
+	if err != nil {
+		t.Fatalf("open fake db: %v", err)
+	}
+
+	previous := database.DB
+	database.DB = db
+	t.Cleanup(func() { database.DB = previous })
+	return f
+}
+
+// On 预设查询结果
+func (f *fakeDB) On(match string, args []interface{}, columns []string, rows ...[]driver.Value) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.results = append(f.results, fakeResult{match: match, args: args, columns: columns, rows: rows})
+}
+
+func (f *fakeDB) query(query string, args []driver.NamedValue) driver.Rows {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.queries = append(f.queries, query)
+
+	for _, result := range f.results {
+		if !strings.Contains(query, result.match) || len(result.args) != len(args) {
+			continue
+		}
+		matched := true
+		for i, arg := range result.args {
+			if fmt.Sprint(arg) != fmt.Sprint(args[i].Value) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return &fakeRows{columns: result.columns, rows: result.rows}
+		}
+	}
+	return &fakeRows{}
+}
+
+func (f *fakeDB) Connect(context.Context) (driver.Conn, error) { return &fakeConn{db: f}, nil }
+func (f *fakeDB) Driver() driver.Driver                        { return nil }
+
+type fakeConn struct {
+	db *fakeDB
+}
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("prepared statements are not supported")
+}
+func (c *fakeConn) Close() error              { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error) { return c, nil }
+func (c *fakeConn) Commit() error             { return nil }
+func (c *fakeConn) Rollback() error           { return nil }
+
+func (c *fakeConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	return c.db.query(query, args), nil
+}
+
+func (c *fakeConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.db.query(query, args)
+	return driver.RowsAffected(1), nil
+}
+
+type fakeRows struct {
+	columns []string
+	rows    [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
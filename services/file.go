@@ -2,6 +2,7 @@ package services
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	_ "image/gif"  // 注册GIF格式
 	_ "image/jpeg" // 注册JPEG格式
@@ -10,11 +11,14 @@ import (
 	"mime/multipart"
 	"path/filepath"
 	"strings"
-	"time"
 
 	"github.com/h2non/filetype"
 	"github.com/h2non/filetype/types"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"pichub.api/constants"
 	"pichub.api/infra/database"
+	"pichub.api/infra/logger"
 	"pichub.api/models"
 	"pichub.api/pkg/utils"
 )
@@ -23,8 +27,18 @@ type FileServiceImpl struct{}
 
 var FileService = &FileServiceImpl{}
 
+// UploadRejectedError 上传内容未通过检查，Reason 说明拒绝原因
+type UploadRejectedError struct {
+	Code   int
+	Reason string
+}
+
+func (e *UploadRejectedError) Error() string {
+	return e.Reason
+}
+
 // UploadFile 处理文件上传
-func (s *FileServiceImpl) UploadFile(file *multipart.FileHeader, userID int, repoID int, isForce bool) (*models.File, error) {
+func (s *FileServiceImpl) UploadFile(file *multipart.FileHeader, userID int, repoID int, opts models.UploadOptions) (*models.File, error) {
 	// 打开文件
 	src, err := file.Open()
 	if err != nil {
@@ -32,161 +46,365 @@ func (s *FileServiceImpl) UploadFile(file *multipart.FileHeader, userID int, rep
 	}
 	defer src.Close()
 
-	// 读取文件内容用于计算哈希值和检测文件类型
-	buf := make([]byte, 512)
-	n, err := src.Read(buf)
-	if err != nil && err != io.EOF {
+	// 先按声明的大小检查上传策略，避免读取超限文件
+	if err := UploadPolicyService.CheckSize(userID, repoID, file.Size); err != nil {
 		return nil, err
 	}
 
-	// 计算文件哈希值
-	src.Seek(0, 0)
-	hashValue, err := utils.CalculateGitHash(src, file.Size)
+	// 读取文件内容，GitHub 上传本身也需要完整内容
+	content, err := io.ReadAll(src)
 	if err != nil {
 		return nil, err
 	}
 
-	// 重要：重置文件指针位置
-	src.Seek(0, 0)
+	return s.saveContent(content, file.Filename, file.Header.Get("Content-Type"), userID, repoID, opts)
+}
 
-	// 如果不是强制上传，检查文件是否已存在
-	if !isForce {
-		var existingFile models.File
-		if err := database.DB.Where("hash_value = ? AND repo_id = ?", hashValue, repoID).First(&existingFile).Error; err == nil {
-			return &existingFile, nil
-		}
+// DeleteFile 将文件移入回收站，保留远程文件，保留期过后由定时任务彻底删除
+func (s *FileServiceImpl) DeleteFile(fileID, userID int) error {
+	// 查找文件记录
+	var file models.File
+	if err := database.DB.Where("id = ? AND user_id = ?", fileID, userID).First(&file).Error; err != nil {
+		return fmt.Errorf("file not found or no permission")
 	}
-	// 已存在的，需要手动删除，程序不去处理了
-
-	// 检测文件类型
-	kind, _ := filetype.Match(buf[:n])
-	fileType := utils.DetermineFileType(kind)
 
-	// 生成唯一文件名
-	ext := filepath.Ext(file.Filename)
-	if ext == "" && kind != types.Unknown {
-		ext = "." + kind.Extension
+	// 软删除，文件列表、搜索和分享中不再可见
+	if err := database.DB.Delete(&file).Error; err != nil {
+		return fmt.Errorf("failed to move file to trash: %v", err)
 	}
-	filename := fmt.Sprintf("%s%s", hashValue, ext)
+	return nil
+}
 
+// DestroyFile 彻底删除文件，包括远程文件和所有关联记录，用于清空回收站
+func (s *FileServiceImpl) DestroyFile(file *models.File) error {
 	// 获取仓库信息
 	var repo models.Repository
-	if err := database.DB.First(&repo, repoID).Error; err != nil {
-		return nil, fmt.Errorf("repository not found")
+	if err := database.DB.First(&repo, file.RepoID).Error; err != nil {
+		return fmt.Errorf("repository not found")
 	}
 
-	// 构建文件路径
-	filePath := utils.BuildFilePath(filename)
+	// 从GitHub删除文件
+	var err error
+	if file.StorageType == "" || file.StorageType == models.FileStorageContents {
+		err = GithubService.DeleteFile(file.UserID, repo.RepoURL, file.URL)
+	} else {
+		err = LargeFileService.Delete(&repo, file)
+	}
+	if err != nil {
+		// 如果是404错误，直接继续删除数据库记录
+		if !strings.Contains(err.Error(), "404") {
+			return fmt.Errorf("failed to delete file from GitHub: %v", err)
+		}
+	}
 
-	// 上传文件到GitHub
-	src.Seek(0, 0)
-	if err := GithubService.UploadFile(userID, repo.RepoURL, filePath, src); err != nil {
-		return nil, err
+	// 删除数据库记录
+	if err := database.DB.Unscoped().Delete(file).Error; err != nil {
+		return fmt.Errorf("failed to delete file record: %v", err)
+	}
+	StorageService.RecordDelete(file)
+	if err := AlbumService.RemoveFile(file.ID); err != nil {
+		logger.Warnf("remove file %d from albums failed: %v", file.ID, err)
+	}
+	if err := TagService.RemoveFile(file.UserID, file.ID); err != nil {
+		logger.Warnf("remove tags of file %d failed: %v", file.ID, err)
 	}
+	if err := SearchService.Remove(file.ID); err != nil {
+		logger.Warnf("remove search index of file %d failed: %v", file.ID, err)
+	}
+	if err := s.RemoveRedirects(file.ID); err != nil {
+		logger.Warnf("remove redirects of file %d failed: %v", file.ID, err)
+	}
+	if err := s.RemoveVersions(file.ID); err != nil {
+		logger.Warnf("remove versions of file %d failed: %v", file.ID, err)
+	}
+	MirrorService.ReplicateDelete(file)
+	PurgeService.PurgeFileAsync(*file, PurgeReasonDelete)
 
-	repoPath := repo.GetRepositoryName()
+	return nil
+}
 
-	// 创建文件记录
-	fileRecord := &models.File{
-		RepoID:      repoID,
-		UserID:      userID,
-		Filename:    filename,
-		URL:         filePath,
-		RepoName:    repoPath,
-		HashValue:   hashValue,
-		RawFilename: file.Filename,
-		Filesize:    uint(file.Size),
-		Mime:        file.Header.Get("Content-Type"),
-		Filetype:    fileType,
+// ToResponses 构建文件响应，按文件所在仓库的地址模板生成地址，并附带镜像上的备用地址
+// 私有仓库的文件返回有时效的签名地址
+func (s *FileServiceImpl) ToResponses(files []models.File) []models.FileResponse {
+	cdnHost := ConfigService.GetFileCDNHostname(0)
+
+	fileIDs := make([]int, 0, len(files))
+	for _, file := range files {
+		fileIDs = append(fileIDs, file.ID)
 	}
+	tags := TagService.FileTags(fileIDs)
 
-	// 如果是图片，获取尺寸信息
-	if fileType == 1 {
-		src.Seek(0, 0) // 重置文件指针
-		if width, height, err := utils.GetImageDimensions(src); err == nil {
-			fileRecord.Width = uint(width)
-			fileRecord.Height = uint(height)
+	repos := map[int]*models.Repository{}
+	for _, file := range files {
+		if _, ok := repos[file.RepoID]; ok {
+			continue
+		}
+		var repo models.Repository
+		if err := database.DB.First(&repo, file.RepoID).Error; err != nil {
+			repos[file.RepoID] = nil
+			continue
 		}
+		repos[file.RepoID] = &repo
 	}
 
-	// 保存到数据库
-	if err := database.DB.Create(fileRecord).Error; err != nil {
-		return nil, err
+	// 镜像地址可以直接访问，私有仓库的文件不返回备用地址
+	public := make([]models.File, 0, len(files))
+	for _, file := range files {
+		if repo := repos[file.RepoID]; repo != nil && !repo.Private {
+			public = append(public, file)
+		}
 	}
+	fallbacks := MirrorService.FallbackURLs(public)
 
-	return fileRecord, nil
+	// 链接模板按用户读取，批量构建时只查询一次
+	templates := map[int]map[string]string{}
+	for _, file := range files {
+		if _, ok := templates[file.UserID]; ok {
+			continue
+		}
+		userTemplates, err := LinkTemplateService.GetTemplates(file.UserID)
+		if err != nil {
+			logger.Warnf("get link templates of user %d failed: %v", file.UserID, err)
+		}
+		templates[file.UserID] = userTemplates
+	}
+
+	response := []models.FileResponse{}
+	for _, file := range files {
+		item := file.ToResponse(cdnHost)
+		if repo := repos[file.RepoID]; repo != nil && repo.Private {
+			// 私有仓库没有公开地址，使用代理下载的签名地址
+			signedURL, expiresAt := SignedURLService.BuildURL(file.ID, SignedURLService.GetTTL(file.UserID))
+			item.FullURL = signedURL
+			item.URLExpiresAt = &expiresAt
+		} else if repo != nil {
+			item.FullURL, item.AltURLs = repo.FileURLs(&file, cdnHost)
+		}
+		item.FallbackURLs = fallbacks[file.ID]
+		item.BuildSnippets()
+		item.Links = LinkTemplateService.Render(templates[file.UserID], &item)
+		item.Tags = tags[file.ID]
+		if item.Tags == nil {
+			item.Tags = []string{}
+		}
+		response = append(response, item)
+	}
+	return response
 }
 
-// DeleteFile 删除文件
-func (s *FileServiceImpl) DeleteFile(fileID, userID int) error {
-	// 查找文件记录
+// GetFile 获取用户的文件
+func (s *FileServiceImpl) GetFile(userID int, fileID int) (*models.File, error) {
 	var file models.File
 	if err := database.DB.Where("id = ? AND user_id = ?", fileID, userID).First(&file).Error; err != nil {
-		return fmt.Errorf("file not found or no permission")
+		return nil, err
 	}
+	return &file, nil
+}
+
+// UpdateMetadata 更新文件的标题、替代文本、描述和自定义元数据
+func (s *FileServiceImpl) UpdateMetadata(userID int, fileID int, req models.UpdateFileMetadataRequest) (*models.File, error) {
+	file, err := s.GetFile(userID, fileID)
+	if err != nil {
+		return nil, fmt.Errorf("file not found")
+	}
+
+	updates := map[string]interface{}{}
+	if req.Title != nil {
+		updates["title"] = strings.TrimSpace(*req.Title)
+	}
+	if req.AltText != nil {
+		updates["alt_text"] = strings.TrimSpace(*req.AltText)
+	}
+	if req.Description != nil {
+		updates["description"] = strings.TrimSpace(*req.Description)
+	}
+	if len(req.Metadata) > 0 {
+		metadata, err := s.normalizeMetadata(req.Metadata)
+		if err != nil {
+			return nil, err
+		}
+		updates["metadata"] = metadata
+	}
+	if len(updates) == 0 {
+		return file, nil
+	}
+
+	if err := database.DB.Model(file).Updates(updates).Error; err != nil {
+		return nil, err
+	}
+
+	// 标题和描述参与全文索引
+	SearchService.Refresh(file.ID)
+	return s.GetFile(userID, fileID)
+}
+
+// normalizeMetadata 校验自定义元数据必须是 JSON 对象，null 表示清空
+func (s *FileServiceImpl) normalizeMetadata(raw json.RawMessage) (string, error) {
+	if string(bytes.TrimSpace(raw)) == "null" {
+		return "", nil
+	}
+
+	var object map[string]interface{}
+	if err := json.Unmarshal(raw, &object); err != nil {
+		return "", fmt.Errorf("metadata must be a JSON object")
+	}
+
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, raw); err != nil {
+		return "", fmt.Errorf("metadata must be a JSON object")
+	}
+	if compact.Len() > constants.MaxFileMetadataSize {
+		return "", fmt.Errorf("metadata must not exceed %d bytes", constants.MaxFileMetadataSize)
+	}
+	return compact.String(), nil
+}
+
+// FileURLs 获取文件的所有访问地址（主地址和备用地址）以及所在仓库
+func (s *FileServiceImpl) FileURLs(file *models.File) ([]string, *models.Repository) {
+	cdnHost := ConfigService.GetFileCDNHostname(0)
 
-	// 获取仓库信息
 	var repo models.Repository
 	if err := database.DB.First(&repo, file.RepoID).Error; err != nil {
-		return fmt.Errorf("repository not found")
+		return []string{file.GetFileURL(cdnHost)}, nil
 	}
 
-	// 从GitHub删除文件
-	err := GithubService.DeleteFile(userID, repo.RepoURL, file.URL)
+	primary, alternates := repo.FileURLs(file, cdnHost)
+	return append([]string{primary}, alternates...), &repo
+}
+
+// ReadContent 从文件的存储位置读取文件内容
+func (s *FileServiceImpl) ReadContent(file *models.File) ([]byte, error) {
+	reader, err := s.OpenContent(file)
 	if err != nil {
-		// 如果是404错误，直接继续删除数据库记录
-		if !strings.Contains(err.Error(), "404") {
-			return fmt.Errorf("failed to delete file from GitHub: %v", err)
-		}
+		return nil, err
 	}
+	defer reader.Close()
 
-	// 删除数据库记录
-	if err := database.DB.Delete(&file).Error; err != nil {
-		return fmt.Errorf("failed to delete file record: %v", err)
+	return io.ReadAll(reader)
+}
+
+// OpenContent 使用文件所有者的 token 打开文件内容，调用方负责关闭
+func (s *FileServiceImpl) OpenContent(file *models.File) (io.ReadCloser, error) {
+	var repo models.Repository
+	if err := database.DB.First(&repo, file.RepoID).Error; err != nil {
+		return nil, fmt.Errorf("repository not found")
 	}
 
-	return nil
+	if file.StorageType == "" || file.StorageType == models.FileStorageContents {
+		return GithubService.OpenFile(repo.UserID, repo.RepoURL, file.URL)
+	}
+	return LargeFileService.Open(&repo, file)
 }
 
 // UploadStream 处理流式文件上传
-func (s *FileServiceImpl) UploadStream(reader io.Reader, filename string, contentType string, fileSize int64, userID int, repoID int, isForce bool) (*models.File, error) {
-	// 读取前512字节用于文件类型检测
-	buf := make([]byte, 512)
-	n, err := reader.Read(buf)
-	if err != nil && err != io.EOF {
+func (s *FileServiceImpl) UploadStream(reader io.Reader, filename string, contentType string, fileSize int64, userID int, repoID int, opts models.UploadOptions) (*models.File, error) {
+	// 先按 Content-Length 检查上传策略，避免读取超限文件
+	if err := UploadPolicyService.CheckSize(userID, repoID, fileSize); err != nil {
 		return nil, err
 	}
 
-	// 创建一个新的reader，将已读取的内容和剩余内容组合
-	combinedReader := io.MultiReader(bytes.NewReader(buf[:n]), reader)
+	// 读取完整的请求体，哈希计算和上传都需要用到
+	// 最多多读一个字节，用于判断请求体是否超过声明的大小或上限
+	limit := fileSize
+	if limit <= 0 {
+		if limit = UploadPolicyService.MaxFileSize(userID, repoID); limit <= 0 {
+			limit = constants.MaxStreamUploadSize
+		}
+	}
+	content, err := io.ReadAll(io.LimitReader(reader, limit+1))
+	if err != nil {
+		return nil, err
+	}
 
-	// 计算文件哈希值
-	hashValue, err := utils.CalculateGitHash(combinedReader, fileSize)
+	if fileSize > 0 && int64(len(content)) != fileSize {
+		return nil, fmt.Errorf("incomplete upload: expected %d bytes, got %d", fileSize, len(content))
+	}
+	if int64(len(content)) > limit {
+		return nil, &UploadRejectedError{
+			Code:   constants.ErrCodeFileTooLarge,
+			Reason: fmt.Sprintf("file size exceeds the limit of %s", utils.FriendlyFileSize(limit)),
+		}
+	}
+	if fileSize <= 0 {
+		if err := UploadPolicyService.CheckSize(userID, repoID, int64(len(content))); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.saveContent(content, filename, contentType, userID, repoID, opts)
+}
+
+// saveContent 上传文件内容到GitHub并保存文件记录，供各种上传方式共用
+func (s *FileServiceImpl) saveContent(content []byte, rawFilename string, contentType string, userID int, repoID int, opts models.UploadOptions) (*models.File, error) {
+	// 安全检查：清理 SVG 活动内容，按策略处理 HTML
+	checked, err := SecurityService.CheckContent(content, rawFilename, contentType)
 	if err != nil {
 		return nil, err
 	}
+	content = checked.Content
+	contentType = checked.ContentType
+
+	// 检测文件类型
+	kind, _ := filetype.Match(content)
+	fileType := utils.DetermineFileType(kind)
+	if checked.IsSVG {
+		fileType = 1
+	} else if checked.Ext == ".txt" {
+		fileType = 4
+	}
+
+	// 检查上传策略，必须在任何 GitHub 请求之前完成
+	width, height, _ := utils.GetImageDimensionsFromBytes(content)
+	if err := UploadPolicyService.Check(userID, repoID, fileType, int64(len(content)), width, height); err != nil {
+		return nil, err
+	}
+
+	// 水印会重新编码图片并丢弃 EXIF，需要先读取
+	var exif string
+	if fileType == 1 {
+		exif = s.readExif(content)
+	}
+
+	// 按配置或请求参数添加水印，需要在计算哈希之前完成
+	if fileType == 1 {
+		watermarked, err := s.applyWatermark(content, kind.Extension, userID, opts)
+		if err != nil {
+			return nil, err
+		}
+		content = watermarked
+	}
 
-	// 重新创建组合reader用于后续操作
-	combinedReader = io.MultiReader(bytes.NewReader(buf[:n]), reader)
+	fileSize := int64(len(content))
+
+	// 计算文件哈希值
+	hashValue, err := utils.CalculateGitHash(bytes.NewReader(content), fileSize)
+	if err != nil {
+		return nil, err
+	}
 
 	// 如果不是强制上传，检查文件是否已存在
-	if !isForce {
+	// 回收站中的相同文件仍占用仓库路径，直接恢复
+	if !opts.IsForce {
 		var existingFile models.File
-		if err := database.DB.Where("hash_value = ? AND repo_id = ?", hashValue, repoID).First(&existingFile).Error; err == nil {
+		if err := database.DB.Unscoped().Where("hash_value = ? AND repo_id = ?", hashValue, repoID).First(&existingFile).Error; err == nil {
+			if existingFile.DeletedAt.Valid {
+				if err := database.DB.Unscoped().Model(&existingFile).Update("deleted_at", nil).Error; err != nil {
+					return nil, err
+				}
+			}
 			return &existingFile, nil
 		}
 	}
-
-	// 检测文件类型
-	kind, _ := filetype.Match(buf[:n])
-	fileType := utils.DetermineFileType(kind)
+	// 已存在的，需要手动删除，程序不去处理了
 
 	// 生成唯一文件名
-	ext := filepath.Ext(filename)
-	if ext == "" && kind != types.Unknown {
+	ext := filepath.Ext(rawFilename)
+	if checked.Ext != "" {
+		ext = checked.Ext
+	} else if ext == "" && kind != types.Unknown {
 		ext = "." + kind.Extension
 	}
-	newFilename := fmt.Sprintf("%s%s", hashValue, ext)
+	filename := fmt.Sprintf("%s%s", hashValue, ext)
 
 	// 获取仓库信息
 	var repo models.Repository
@@ -195,73 +413,190 @@ func (s *FileServiceImpl) UploadStream(reader io.Reader, filename string, conten
 	}
 
 	// 构建文件路径
-	filePath := utils.BuildFilePath(newFilename)
-
-	// 上传文件到GitHub
-	if err := GithubService.UploadFile(userID, repo.RepoURL, filePath, combinedReader); err != nil {
-		return nil, err
-	}
-
+	filePath := utils.BuildFilePath(filename)
 	repoPath := repo.GetRepositoryName()
 
 	// 创建文件记录
 	fileRecord := &models.File{
 		RepoID:      repoID,
 		UserID:      userID,
-		Filename:    newFilename,
+		Filename:    filename,
 		URL:         filePath,
 		RepoName:    repoPath,
 		HashValue:   hashValue,
-		RawFilename: filename,
+		RawFilename: rawFilename,
 		Filesize:    uint(fileSize),
 		Mime:        contentType,
 		Filetype:    fileType,
+		Exif:        exif,
 	}
 
-	// 如果是图片，获取尺寸信息
-	if fileType == 1 {
-		fullURL := fileRecord.GetFileURL(ConfigService.GetFileCDNHostname(0))
-
-		// 重试最多3次
-		for i := 0; i < 3; i++ {
-			if width, height, err := utils.GetImageDimensionsFromURL(fullURL); err == nil {
-				fileRecord.Width = uint(width)
-				fileRecord.Height = uint(height)
-				break
-			}
-			time.Sleep(time.Second * time.Duration(i+1)) // 递增延迟
+	// 如果是位图，获取尺寸、占位图和感知哈希
+	if fileType == 1 && !checked.IsSVG {
+		s.fillImageMeta(fileRecord, content)
+	}
+
+	// 复用模式下，用户已有近似图片则直接返回，不再上传
+	if opts.OnSimilar == constants.SimilarModeReuse && fileRecord.Phash != 0 && !opts.IsForce {
+		similar, err := s.findSimilarByHash(userID, fileRecord.Phash, s.GetSimilarDistance(userID), 0)
+		if err == nil && len(similar) > 0 {
+			return &similar[0], nil
 		}
 	}
 
+	// 检查存储配额，按添加水印后的实际大小计算
+	// 放在去重之后，已存在或从回收站恢复的文件不占用新的空间
+	if err := StorageService.CheckQuota(userID, repoID, fileSize); err != nil {
+		return nil, err
+	}
+
+	// 上传文件到GitHub，超过阈值的大文件改用 release 附件或 LFS
+	storageType := LargeFileService.StorageFor(userID, fileSize)
+	if storageType == models.FileStorageContents {
+		if err := GithubService.UploadFile(userID, repo.RepoURL, filePath, bytes.NewReader(content)); err != nil {
+			return nil, err
+		}
+	} else if err := LargeFileService.Upload(&repo, fileRecord, storageType, content); err != nil {
+		return nil, err
+	}
+
 	// 保存到数据库
 	if err := database.DB.Create(fileRecord).Error; err != nil {
 		return nil, err
 	}
+	StorageService.RecordCreate(fileRecord)
+	MirrorService.ReplicateUpload(fileRecord, content)
+	SearchService.Refresh(fileRecord.ID)
+
+	// 强制上传可能覆盖同一路径下的旧内容，刷新 CDN 缓存
+	if opts.IsForce {
+		PurgeService.PurgeFileAsync(*fileRecord, PurgeReasonReplace)
+	}
 
 	return fileRecord, nil
 }
 
-// ListFiles 列出文件
-func (s *FileServiceImpl) ListFiles(userID int, repoID int, page int, pageSize int) ([]models.File, int64, error) {
+// applyWatermark 根据水印配置处理图片内容，不需要水印时原样返回
+func (s *FileServiceImpl) applyWatermark(content []byte, format string, userID int, opts models.UploadOptions) ([]byte, error) {
+	if opts.Watermark != nil && !*opts.Watermark {
+		return content, nil
+	}
+
+	config, err := WatermarkService.GetConfig(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load watermark config: %v", err)
+	}
+
+	if !WatermarkService.ShouldApply(config, opts.Watermark) {
+		return content, nil
+	}
+
+	return WatermarkService.Apply(config, content, format)
+}
+
+// fillImageMeta 计算图片尺寸、BlurHash、低质量占位图和主色调
+// 解码失败（如 svg、webp）时只跳过对应字段，不影响上传
+func (s *FileServiceImpl) fillImageMeta(fileRecord *models.File, content []byte) {
+	if width, height, err := utils.GetImageDimensionsFromBytes(content); err == nil {
+		fileRecord.Width = uint(width)
+		fileRecord.Height = uint(height)
+	}
+
+	img, err := utils.DecodeImage(content)
+	if err != nil {
+		logger.Warnf("decode image %s failed, skip placeholders: %v", fileRecord.Filename, err)
+		return
+	}
+
+	if hash, err := utils.BlurHash(img, 4, 3); err == nil {
+		fileRecord.BlurHash = hash
+	}
+	if lqip, err := utils.LQIP(img, 16); err == nil {
+		fileRecord.Lqip = lqip
+	}
+	fileRecord.DominantColor = utils.DominantColor(img)
+	fileRecord.Phash = utils.DHash(img)
+}
+
+// GetSimilarDistance 获取近似图片的汉明距离阈值，可通过 file.similar_distance 配置
+func (s *FileServiceImpl) GetSimilarDistance(userID int) int {
+	value, err := ConfigService.Get("file", "similar_distance", userID)
+	if err != nil || utils.IsEmpty(value) {
+		value, _ = ConfigService.Get("file", "similar_distance", 0)
+	}
+
+	distance := utils.ToInt(value, constants.DefaultSimilarDistance)
+	if distance < 0 || distance > constants.MaxSimilarDistance {
+		return constants.DefaultSimilarDistance
+	}
+	return distance
+}
+
+// FindSimilarFiles 在用户所有仓库中查找与指定文件近似的图片
+func (s *FileServiceImpl) FindSimilarFiles(userID int, fileID int, distance int) ([]models.File, uint64, error) {
+	var file models.File
+	if err := database.DB.Where("id = ? AND user_id = ?", fileID, userID).First(&file).Error; err != nil {
+		return nil, 0, fmt.Errorf("file not found or no permission")
+	}
+
+	if file.Phash == 0 {
+		return nil, 0, fmt.Errorf("file has no perceptual hash")
+	}
+
+	files, err := s.findSimilarByHash(userID, file.Phash, distance, file.ID)
+	if err != nil {
+		return nil, 0, err
+	}
+	return files, file.Phash, nil
+}
+
+// findSimilarByHash 按汉明距离从近到远查找近似图片
+func (s *FileServiceImpl) findSimilarByHash(userID int, phash uint64, distance int, excludeID int) ([]models.File, error) {
+	var files []models.File
+
+	query := database.DB.Where("user_id = ? AND phash <> 0 AND BIT_COUNT(phash ^ ?) <= ?", userID, phash, distance)
+	if excludeID > 0 {
+		query = query.Where("id <> ?", excludeID)
+	}
+
+	err := query.Clauses(clause.OrderBy{
+		Expression: clause.Expr{SQL: "BIT_COUNT(phash ^ ?), id DESC", Vars: []interface{}{phash}},
+	}).Limit(constants.MaxSimilarResults).Find(&files).Error
+	if err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// ListFiles 按筛选条件列出文件，指定游标时从游标位置开始而不是按页码偏移
+func (s *FileServiceImpl) ListFiles(userID int, filter models.FileFilter, page int, pageSize int) ([]models.File, int64, error) {
 	var total int64
 	var files []models.File
 
 	// 构建基础查询
-	query := database.DB.Where("user_id = ?", userID)
+	query := database.DB.Model(&models.File{}).Where(fileTable()+".user_id = ?", userID)
 
-	// 如果指定了仓库ID，添加仓库筛选条件
-	if repoID > 0 {
-		query = query.Where("repo_id = ?", repoID)
-	}
+	// 添加筛选条件
+	query = s.applyFilter(query, userID, filter)
 
 	// 获取总记录数
-	if err := query.Model(&models.File{}).Count(&total).Error; err != nil {
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
-	// 添加分页查询，并按ID降序排序
+	// 添加排序和游标条件
+	query, err := s.applyOrder(query, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// 添加分页查询
 	offset := (page - 1) * pageSize
-	if err := query.Order("id DESC").Offset(offset).Limit(pageSize).Find(&files).Error; err != nil {
+	if filter.Cursor != "" {
+		offset = 0
+	}
+	if err := query.Select(fileTable() + ".*").Offset(offset).Limit(pageSize).Find(&files).Error; err != nil {
 		return nil, 0, err
 	}
 
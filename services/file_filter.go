@@ -0,0 +1,186 @@
+package services
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"pichub.api/helpers"
+	"pichub.api/infra/database"
+	"pichub.api/models"
+)
+
+var (
+	ErrInvalidCursor     = errors.New("invalid cursor")
+	ErrCursorUnsupported = errors.New("cursor pagination is not supported for album order, specify a sort field")
+)
+
+// fileCursor 游标中保存上一页最后一个文件的排序值和ID
+type fileCursor struct {
+	Value string `json:"v,omitempty"`
+	ID    int    `json:"id"`
+}
+
+// NextCursor 生成下一页的游标，没有更多数据或当前排序不支持游标时返回空
+func (s *FileServiceImpl) NextCursor(filter models.FileFilter, files []models.File, pageSize int) string {
+	if len(files) == 0 || len(files) < pageSize {
+		return ""
+	}
+	if filter.Sort == models.FileSortDefault && filter.AlbumID > 0 {
+		return ""
+	}
+
+	last := files[len(files)-1]
+	cursor := fileCursor{ID: last.ID}
+	switch filter.Sort {
+	case models.FileSortDate:
+		cursor.Value = last.CreatedAt.UTC().Format(time.RFC3339Nano)
+	case models.FileSortSize:
+		cursor.Value = strconv.FormatUint(uint64(last.Filesize), 10)
+	case models.FileSortName:
+		cursor.Value = last.RawFilename
+	}
+
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// applyFilter 为文件查询添加筛选条件
+func (s *FileServiceImpl) applyFilter(query *gorm.DB, userID int, filter models.FileFilter) *gorm.DB {
+	table := fileTable()
+
+	// 指定相册时只返回相册中的文件，相册内的排序依赖这里关联的 af
+	if filter.AlbumID > 0 {
+		albumFileTable := database.DB.NamingStrategy.TableName("AlbumFile")
+		query = query.Joins(fmt.Sprintf("JOIN %s af ON af.file_id = %s.id AND af.album_id = ?", albumFileTable, table), filter.AlbumID)
+	}
+
+	if filter.RepoID > 0 {
+		query = query.Where(table+".repo_id = ?", filter.RepoID)
+	}
+
+	query = query.Scopes(helpers.Search(filter.Keyword, table+".raw_filename"))
+
+	if filter.Mime != "" {
+		if prefix, ok := strings.CutSuffix(filter.Mime, "*"); ok {
+			query = query.Where(table+".mime LIKE ?", helpers.EscapeLike(prefix)+"%")
+		} else {
+			query = query.Where(table+".mime = ?", filter.Mime)
+		}
+	}
+
+	if len(filter.Filetypes) > 0 {
+		query = query.Where(table+".filetype IN ?", filter.Filetypes)
+	}
+
+	query = s.applyRange(query, table+".filesize", filter.MinSize, filter.MaxSize)
+	query = s.applyRange(query, table+".width", filter.MinWidth, filter.MaxWidth)
+	query = s.applyRange(query, table+".height", filter.MinHeight, filter.MaxHeight)
+
+	// 上传时间的结束时间不包含在内
+	if filter.CreatedFrom != nil {
+		query = query.Where(table+".created_at >= ?", *filter.CreatedFrom)
+	}
+	if filter.CreatedTo != nil {
+		query = query.Where(table+".created_at < ?", *filter.CreatedTo)
+	}
+
+	// 按标签筛选
+	return TagService.ApplyFilter(query, userID, filter.Tags, filter.TagMode)
+}
+
+// applyRange 添加数值范围条件，0 表示不限制
+func (s *FileServiceImpl) applyRange(query *gorm.DB, column string, min uint, max uint) *gorm.DB {
+	if min > 0 {
+		query = query.Where(column+" >= ?", min)
+	}
+	if max > 0 {
+		query = query.Where(column+" <= ?", max)
+	}
+	return query
+}
+
+// applyOrder 添加排序，以文件ID作为相同排序值时的次序，保证游标分页的结果稳定
+func (s *FileServiceImpl) applyOrder(query *gorm.DB, filter models.FileFilter) (*gorm.DB, error) {
+	table := fileTable()
+	direction, op := "DESC", "<"
+	if filter.Asc {
+		direction, op = "ASC", ">"
+	}
+
+	column := s.sortColumn(filter.Sort)
+	if column == "" {
+		// 相册默认按相册内的顺序排序
+		if filter.AlbumID > 0 {
+			if filter.Cursor != "" {
+				return nil, ErrCursorUnsupported
+			}
+			return query.Order("af.sort_order ASC, " + table + ".id DESC"), nil
+		}
+		column = table + ".id"
+	}
+
+	if filter.Cursor != "" {
+		cursor, value, err := s.decodeCursor(filter)
+		if err != nil {
+			return nil, err
+		}
+		if column == table+".id" {
+			query = query.Where(fmt.Sprintf("%s.id %s ?", table, op), cursor.ID)
+		} else {
+			query = query.Where(fmt.Sprintf("(%s %s ? OR (%s = ? AND %s.id %s ?))", column, op, column, table, op), value, value, cursor.ID)
+		}
+	}
+
+	if column == table+".id" {
+		return query.Order(column + " " + direction), nil
+	}
+	return query.Order(fmt.Sprintf("%s %s, %s.id %s", column, direction, table, direction)), nil
+}
+
+// sortColumn 排序字段对应的列，默认排序返回空
+func (s *FileServiceImpl) sortColumn(sort string) string {
+	switch sort {
+	case models.FileSortDate:
+		return fileTable() + ".created_at"
+	case models.FileSortSize:
+		return fileTable() + ".filesize"
+	case models.FileSortName:
+		return fileTable() + ".raw_filename"
+	}
+	return ""
+}
+
+// decodeCursor 解析游标，并按排序字段转换排序值的类型
+func (s *FileServiceImpl) decodeCursor(filter models.FileFilter) (*fileCursor, interface{}, error) {
+	data, err := base64.RawURLEncoding.DecodeString(filter.Cursor)
+	if err != nil {
+		return nil, nil, ErrInvalidCursor
+	}
+
+	var cursor fileCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID <= 0 {
+		return nil, nil, ErrInvalidCursor
+	}
+
+	switch filter.Sort {
+	case models.FileSortDate:
+		value, err := time.Parse(time.RFC3339Nano, cursor.Value)
+		if err != nil {
+			return nil, nil, ErrInvalidCursor
+		}
+		return &cursor, value, nil
+	case models.FileSortSize:
+		value, err := strconv.ParseUint(cursor.Value, 10, 64)
+		if err != nil {
+			return nil, nil, ErrInvalidCursor
+		}
+		return &cursor, value, nil
+	}
+	return &cursor, cursor.Value, nil
+}
@@ -0,0 +1,192 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"pichub.api/constants"
+	"pichub.api/infra/database"
+	"pichub.api/infra/logger"
+	"pichub.api/models"
+	"pichub.api/pkg/utils"
+)
+
+var (
+	ErrRedirectNotFound      = errors.New("redirect not found")
+	ErrRedirectToPrivateRepo = errors.New("keep_redirect is not available when moving to a private repository")
+)
+
+// MoveFile 修改文件在仓库中的路径，或移动到用户的另一个仓库
+// 先复制到新位置并校验内容，再在一个事务中更新文件记录，最后删除旧位置的文件
+func (s *FileServiceImpl) MoveFile(userID int, fileID int, req models.MoveFileRequest) (*models.File, error) {
+	file, err := s.GetFile(userID, fileID)
+	if err != nil {
+		return nil, fmt.Errorf("file not found")
+	}
+	if file.StorageType != "" && file.StorageType != models.FileStorageContents {
+		return nil, fmt.Errorf("moving files stored as %s is not supported", file.StorageType)
+	}
+
+	var source models.Repository
+	if err := database.DB.First(&source, file.RepoID).Error; err != nil {
+		return nil, fmt.Errorf("repository not found")
+	}
+
+	target := source
+	if req.RepoID > 0 && req.RepoID != source.ID {
+		repo, err := RepositoryService.GetRepository(userID, req.RepoID)
+		if err != nil {
+			return nil, fmt.Errorf("target repository not found")
+		}
+		target = *repo
+	}
+	if target.Private && req.KeepRedirect {
+		return nil, ErrRedirectToPrivateRepo
+	}
+
+	newPath := file.URL
+	if req.Path != "" {
+		if newPath, err = s.cleanPath(req.Path); err != nil {
+			return nil, err
+		}
+	}
+	if target.ID == source.ID && newPath == file.URL {
+		return nil, fmt.Errorf("file is already at %s", newPath)
+	}
+
+	if target.ID != source.ID {
+		if err := StorageService.CheckQuota(userID, target.ID, int64(file.Filesize)); err != nil {
+			return nil, err
+		}
+	}
+
+	// 复制到新位置
+	content, err := s.ReadContent(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %v", err)
+	}
+	message := fmt.Sprintf("Move file: %s", file.RawFilename)
+	if err := GithubService.CommitFile(userID, target.RepoURL, newPath, content, message); err != nil {
+		return nil, err
+	}
+
+	// 校验新位置的内容与原文件一致
+	if err := s.verifyCopy(userID, &target, newPath, content); err != nil {
+		s.removeCopy(userID, &target, newPath)
+		return nil, err
+	}
+
+	old := *file
+	updates := map[string]interface{}{
+		"url":       newPath,
+		"filename":  path.Base(newPath),
+		"repo_id":   target.ID,
+		"repo_name": target.GetRepositoryName(),
+	}
+	if err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.File{}).Where("id = ?", file.ID).Updates(updates).Error; err != nil {
+			return err
+		}
+		// 移入私有仓库后，之前移动留下的旧地址也不再跳转
+		if target.Private {
+			if err := tx.Where("file_id = ?", file.ID).Delete(&models.FileRedirect{}).Error; err != nil {
+				return err
+			}
+		}
+		if !req.KeepRedirect {
+			return nil
+		}
+		return tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "repo_id"}, {Name: "path"}},
+			DoUpdates: clause.AssignmentColumns([]string{"file_id", "user_id"}),
+		}).Create(&models.FileRedirect{UserID: userID, FileID: file.ID, RepoID: old.RepoID, Path: old.URL}).Error
+	}); err != nil {
+		s.removeCopy(userID, &target, newPath)
+		return nil, err
+	}
+
+	moved, err := s.GetFile(userID, fileID)
+	if err != nil {
+		return nil, err
+	}
+
+	// 删除旧位置的文件，失败时只留下无记录的旧文件，不影响新地址
+	if err := GithubService.DeleteFile(userID, source.RepoURL, old.URL); err != nil && !strings.Contains(err.Error(), "404") {
+		logger.Warnf("delete file %s from %s after move failed: %v", old.URL, source.RepoURL, err)
+	}
+
+	if old.RepoID != moved.RepoID {
+		StorageService.RecordDelete(&old)
+		StorageService.RecordCreate(moved)
+	}
+	MirrorService.ReplicateDelete(&old)
+	MirrorService.ReplicateUpload(moved, content)
+	PurgeService.PurgeFileAsync(old, PurgeReasonDelete)
+
+	return moved, nil
+}
+
+// RemoveRedirects 文件删除后清理指向它的跳转记录
+func (s *FileServiceImpl) RemoveRedirects(fileID int) error {
+	return database.DB.Where("file_id = ?", fileID).Delete(&models.FileRedirect{}).Error
+}
+
+// ResolveRedirect 根据移动前的仓库和路径查找文件
+func (s *FileServiceImpl) ResolveRedirect(repoID int, filePath string) (*models.File, error) {
+	var redirect models.FileRedirect
+	if err := database.DB.Where("repo_id = ? AND path = ?", repoID, strings.TrimPrefix(filePath, "/")).
+		First(&redirect).Error; err != nil {
+		return nil, ErrRedirectNotFound
+	}
+
+	file, err := s.GetFile(redirect.UserID, redirect.FileID)
+	if err != nil {
+		return nil, ErrRedirectNotFound
+	}
+	return file, nil
+}
+
+// cleanPath 规范化目标路径，不允许跳出仓库或写入 LFS 目录
+func (s *FileServiceImpl) cleanPath(raw string) (string, error) {
+	cleaned := path.Clean("/" + strings.TrimSpace(raw))[1:]
+	if cleaned == "" || strings.HasSuffix(raw, "/") {
+		return "", fmt.Errorf("invalid path: %s", raw)
+	}
+	for _, segment := range strings.Split(cleaned, "/") {
+		if segment == ".." || strings.HasPrefix(segment, ".") {
+			return "", fmt.Errorf("invalid path: %s", raw)
+		}
+	}
+	if cleaned == constants.LargeFileLFSDir || strings.HasPrefix(cleaned, constants.LargeFileLFSDir+"/") {
+		return "", fmt.Errorf("path %s is reserved for large files", constants.LargeFileLFSDir)
+	}
+	return cleaned, nil
+}
+
+// verifyCopy 比较新位置的 blob SHA 与内容的 git 哈希
+func (s *FileServiceImpl) verifyCopy(userID int, repo *models.Repository, filePath string, content []byte) error {
+	expected, err := utils.CalculateGitHash(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return err
+	}
+	sha, err := GithubService.GetFileSHA(userID, repo.RepoURL, filePath)
+	if err != nil {
+		return fmt.Errorf("failed to verify moved file: %v", err)
+	}
+	if sha != expected {
+		return fmt.Errorf("moved file verification failed: expected %s, got %s", expected, sha)
+	}
+	return nil
+}
+
+// removeCopy 移动失败时删除已复制的文件
+func (s *FileServiceImpl) removeCopy(userID int, repo *models.Repository, filePath string) {
+	if err := GithubService.DeleteFile(userID, repo.RepoURL, filePath); err != nil {
+		logger.Warnf("remove copied file %s from %s failed: %v", filePath, repo.RepoURL, err)
+	}
+}
@@ -0,0 +1,296 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/h2non/filetype"
+	"github.com/h2non/filetype/types"
+	"gorm.io/gorm"
+	"pichub.api/infra/database"
+	"pichub.api/infra/logger"
+	"pichub.api/models"
+	"pichub.api/pkg/utils"
+)
+
+var ErrFileVersionNotFound = errors.New("file version not found")
+
+// replacement 替换后的文件内容及其元数据
+type replacement struct {
+	content     []byte
+	rawFilename string
+	mime        string
+	filetype    uint8
+	exif        string
+}
+
+// ReplaceFile 用新内容覆盖文件，路径和访问地址保持不变，覆盖前的版本记录到版本表
+func (s *FileServiceImpl) ReplaceFile(userID int, fileID int, upload *multipart.FileHeader, opts models.UploadOptions) (*models.File, error) {
+	file, repo, err := s.versionedFile(userID, fileID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := UploadPolicyService.CheckSize(userID, repo.ID, upload.Size); err != nil {
+		return nil, err
+	}
+	src, err := upload.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+	content, err := io.ReadAll(src)
+	if err != nil {
+		return nil, err
+	}
+	rawFilename := upload.Filename
+	contentType := upload.Header.Get("Content-Type")
+
+	// 与上传相同的安全检查和上传策略
+	checked, err := SecurityService.CheckContent(content, rawFilename, contentType)
+	if err != nil {
+		return nil, err
+	}
+	content = checked.Content
+
+	kind, _ := filetype.Match(content)
+	fileType := utils.DetermineFileType(kind)
+	if checked.IsSVG {
+		fileType = 1
+	} else if checked.Ext == ".txt" {
+		fileType = 4
+	}
+
+	// 路径中的扩展名决定 CDN 返回的类型，不允许替换为其他格式
+	ext := filepath.Ext(rawFilename)
+	if checked.Ext != "" {
+		ext = checked.Ext
+	} else if kind != types.Unknown {
+		ext = "." + kind.Extension
+	}
+	if !strings.EqualFold(ext, path.Ext(file.URL)) {
+		return nil, fmt.Errorf("replacement must be a %s file", path.Ext(file.URL))
+	}
+
+	width, height, _ := utils.GetImageDimensionsFromBytes(content)
+	if err := UploadPolicyService.Check(userID, repo.ID, fileType, int64(len(content)), width, height); err != nil {
+		return nil, err
+	}
+
+	var exif string
+	if fileType == 1 {
+		exif = s.readExif(content)
+		watermarked, err := s.applyWatermark(content, kind.Extension, userID, opts)
+		if err != nil {
+			return nil, err
+		}
+		content = watermarked
+	}
+
+	return s.writeVersion(file, repo, replacement{
+		content:     content,
+		rawFilename: rawFilename,
+		mime:        checked.ContentType,
+		filetype:    fileType,
+		exif:        exif,
+	}, models.FileVersionReplace)
+}
+
+// ListVersions 获取文件的历史版本，最新的在前
+func (s *FileServiceImpl) ListVersions(userID int, fileID int) ([]models.FileVersion, error) {
+	if _, err := s.GetFile(userID, fileID); err != nil {
+		return nil, fmt.Errorf("file not found")
+	}
+
+	versions := []models.FileVersion{}
+	if err := database.DB.Where("file_id = ?", fileID).Order("version DESC").Find(&versions).Error; err != nil {
+		return nil, err
+	}
+	return versions, nil
+}
+
+// RollbackFile 将文件恢复为历史版本的内容，当前内容同样记录为一个版本，回滚可以撤销
+func (s *FileServiceImpl) RollbackFile(userID int, fileID int, versionID int) (*models.File, error) {
+	file, repo, err := s.versionedFile(userID, fileID)
+	if err != nil {
+		return nil, err
+	}
+
+	var version models.FileVersion
+	if err := database.DB.Where("id = ? AND file_id = ?", versionID, fileID).First(&version).Error; err != nil {
+		return nil, ErrFileVersionNotFound
+	}
+
+	// 文件移动后，历史版本的 blob 仍在原仓库中
+	source := repo
+	if version.RepoID != repo.ID {
+		if source, err = RepositoryService.GetRepository(userID, version.RepoID); err != nil {
+			return nil, fmt.Errorf("repository of version %d not found", version.Version)
+		}
+	}
+
+	content, err := GithubService.GetBlob(userID, source.RepoURL, version.BlobSHA)
+	if err != nil {
+		return nil, err
+	}
+
+	var exif string
+	if version.Filetype == 1 {
+		exif = s.readExif(content)
+	}
+
+	return s.writeVersion(file, repo, replacement{
+		content:     content,
+		rawFilename: version.RawFilename,
+		mime:        version.Mime,
+		filetype:    version.Filetype,
+		exif:        exif,
+	}, models.FileVersionRollback)
+}
+
+// RemoveVersions 文件彻底删除后清理版本记录
+func (s *FileServiceImpl) RemoveVersions(fileID int) error {
+	return database.DB.Where("file_id = ?", fileID).Delete(&models.FileVersion{}).Error
+}
+
+// versionedFile 获取支持版本管理的文件及其仓库，只有通过 Contents API 存储的文件有 blob 历史
+func (s *FileServiceImpl) versionedFile(userID int, fileID int) (*models.File, *models.Repository, error) {
+	file, err := s.GetFile(userID, fileID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("file not found")
+	}
+	if file.StorageType != "" && file.StorageType != models.FileStorageContents {
+		return nil, nil, fmt.Errorf("replacing files stored as %s is not supported", file.StorageType)
+	}
+
+	var repo models.Repository
+	if err := database.DB.First(&repo, file.RepoID).Error; err != nil {
+		return nil, nil, fmt.Errorf("repository not found")
+	}
+	return file, &repo, nil
+}
+
+// writeVersion 以文件当前的 blob SHA 覆盖仓库中的内容，记录旧版本并更新文件记录
+func (s *FileServiceImpl) writeVersion(file *models.File, repo *models.Repository, next replacement, reason string) (*models.File, error) {
+	size := int64(len(next.content))
+	if delta := size - int64(file.Filesize); delta > 0 {
+		if err := StorageService.CheckQuota(file.UserID, repo.ID, delta); err != nil {
+			return nil, err
+		}
+	}
+
+	hashValue, err := utils.CalculateGitHash(bytes.NewReader(next.content), size)
+	if err != nil {
+		return nil, err
+	}
+
+	sha, err := GithubService.GetFileSHA(file.UserID, repo.RepoURL, file.URL)
+	if err != nil {
+		return nil, err
+	}
+	if sha == hashValue {
+		return nil, fmt.Errorf("content is identical to the current version")
+	}
+
+	message := fmt.Sprintf("Replace file: %s", file.RawFilename)
+	if reason == models.FileVersionRollback {
+		message = fmt.Sprintf("Rollback file: %s", file.RawFilename)
+	}
+	newSHA, err := GithubService.UpdateFile(file.UserID, repo.RepoURL, file.URL, next.content, message, sha)
+	if err != nil {
+		return nil, err
+	}
+
+	// 重新计算尺寸和占位图，非位图时清空
+	meta := &models.File{Filename: file.Filename}
+	if next.filetype == 1 {
+		s.fillImageMeta(meta, next.content)
+	}
+
+	old := *file
+	updates := map[string]interface{}{
+		"hash_value":     hashValue,
+		"raw_filename":   next.rawFilename,
+		"filesize":       uint(size),
+		"mime":           next.mime,
+		"filetype":       next.filetype,
+		"exif":           next.exif,
+		"width":          meta.Width,
+		"height":         meta.Height,
+		"blur_hash":      meta.BlurHash,
+		"lqip":           meta.Lqip,
+		"dominant_color": meta.DominantColor,
+		"phash":          meta.Phash,
+	}
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		var latest int
+		if err := tx.Model(&models.FileVersion{}).Where("file_id = ?", file.ID).
+			Select("COALESCE(MAX(version), 0)").Scan(&latest).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&models.FileVersion{
+			FileID:      file.ID,
+			UserID:      file.UserID,
+			RepoID:      repo.ID,
+			Version:     latest + 1,
+			BlobSHA:     sha,
+			HashValue:   old.HashValue,
+			RawFilename: old.RawFilename,
+			Filesize:    old.Filesize,
+			Width:       old.Width,
+			Height:      old.Height,
+			Mime:        old.Mime,
+			Filetype:    old.Filetype,
+			Reason:      reason,
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.File{}).Where("id = ?", file.ID).Updates(updates).Error
+	})
+	if err != nil {
+		s.revertVersion(file, repo, sha, newSHA)
+		return nil, err
+	}
+
+	updated, err := s.GetFile(file.UserID, file.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	StorageService.RecordDelete(&old)
+	StorageService.RecordCreate(updated)
+	MirrorService.ReplicateUpload(updated, next.content)
+	SearchService.Refresh(updated.ID)
+	PurgeService.PurgeFileAsync(*updated, PurgeReasonReplace)
+
+	return updated, nil
+}
+
+// revertVersion 记录保存失败时恢复仓库中的旧内容，失败只记录日志
+func (s *FileServiceImpl) revertVersion(file *models.File, repo *models.Repository, oldSHA string, currentSHA string) {
+	content, err := GithubService.GetBlob(file.UserID, repo.RepoURL, oldSHA)
+	if err == nil {
+		message := fmt.Sprintf("Revert file: %s", file.RawFilename)
+		_, err = GithubService.UpdateFile(file.UserID, repo.RepoURL, file.URL, content, message, currentSHA)
+	}
+	if err != nil {
+		logger.Warnf("revert file %d to blob %s failed: %v", file.ID, oldSHA, err)
+	}
+}
+
+// readExif 读取图片的 EXIF 文本字段，没有时返回空字符串
+func (s *FileServiceImpl) readExif(content []byte) string {
+	fields := utils.ReadExif(content)
+	if len(fields) == 0 {
+		return ""
+	}
+	data, _ := json.Marshal(fields)
+	return string(data)
+}
@@ -2,14 +2,17 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httputil"
+	"os"
 	"path/filepath"
 	"strings"
 
 	"github.com/google/go-github/v65/github"
+	"github.com/spf13/viper"
 	"pichub.api/config"
 	"pichub.api/infra/database"
 	"pichub.api/infra/logger"
@@ -19,10 +22,15 @@ import (
 
 type GithubServiceImpl struct {
 	client *github.Client
+	// tokenSource 获取用户 token 的方式，为空时从配置读取，测试时替换
+	tokenSource func(userID int) (string, error)
 }
 
 var GithubService = &GithubServiceImpl{}
 
+// ErrGithubFileNotFound 仓库中不存在该文件
+var ErrGithubFileNotFound = errors.New("file not found in repository")
+
 // 方法1：创建一个调试用的 Transport
 type debugTransport struct {
 	t http.RoundTripper
@@ -66,6 +74,12 @@ func (s *GithubServiceImpl) getClient(token string) *github.Client {
 	}
 
 	client := github.NewClient(httpClient)
+	// 可指向本地的模拟 API，便于测试
+	if apiURL := githubAPIURL(); apiURL != "" {
+		if c, err := client.WithEnterpriseURLs(apiURL, apiURL); err == nil {
+			client = c
+		}
+	}
 	if token != "" {
 		client = client.WithAuthToken(token)
 	}
@@ -105,6 +119,19 @@ func (s *GithubServiceImpl) ValidateRepository(repoURL string, token string, bra
 	return owner, repo, nil
 }
 
+// IsRepositoryPrivate 查询仓库是否为私有仓库
+func (s *GithubServiceImpl) IsRepositoryPrivate(repoURL string, token string) (bool, error) {
+	owner, repo := splitRepoURL(repoURL)
+
+	client := s.getClient(token)
+	info, _, err := client.Repositories.Get(context.Background(), owner, repo)
+	if err != nil {
+		return false, fmt.Errorf("repository not found or not accessible")
+	}
+
+	return info.GetPrivate(), nil
+}
+
 // InitializeRepository 初始化仓库数据
 func (s *GithubServiceImpl) InitializeRepository(repo *models.Repository, token string) error {
 	parts := strings.Split(strings.TrimSuffix(repo.RepoURL, "/"), "/")
@@ -194,26 +221,31 @@ func (s *GithubServiceImpl) processContent(ctx context.Context, client *github.C
 
 // UploadFile 上传文件到GitHub仓库
 func (s *GithubServiceImpl) UploadFile(userID int, repoURL string, remotePath string, file io.Reader) error {
-	// 从URL中提取owner和repo名称
-	parts := strings.Split(strings.TrimSuffix(repoURL, "/"), "/")
-	owner := parts[len(parts)-2]
-	repo := parts[len(parts)-1]
-
 	// 读取文件内容
 	content, err := io.ReadAll(file)
 	if err != nil {
 		return fmt.Errorf("failed to read file: %v", err)
 	}
 
+	return s.CommitFile(userID, repoURL, remotePath, content, fmt.Sprintf("Upload backup file: %s", filepath.Base(remotePath)))
+}
+
+// CommitFile 以指定的提交信息在GitHub仓库中创建文件
+func (s *GithubServiceImpl) CommitFile(userID int, repoURL string, remotePath string, content []byte, message string) error {
+	// 从URL中提取owner和repo名称
+	parts := strings.Split(strings.TrimSuffix(repoURL, "/"), "/")
+	owner := parts[len(parts)-2]
+	repo := parts[len(parts)-1]
+
 	// 准备文件上传参数
 	opts := &github.RepositoryContentFileOptions{
-		Message: github.String(fmt.Sprintf("Upload backup file: %s", filepath.Base(remotePath))),
+		Message: github.String(message),
 		Content: content,
 		// Branch:  github.String("main"), // 指定上传分支，可忽略
 	}
 
 	// 获取token
-	token, err := ConfigService.GetGithubToken(userID)
+	token, err := s.userToken(userID)
 	if err != nil {
 		return fmt.Errorf("failed to get github token: %v", err)
 	}
@@ -272,7 +304,7 @@ func (s *GithubServiceImpl) DeleteFile(userID int, repoURL string, remotePath st
 	repo := parts[len(parts)-1]
 
 	// 获取token
-	token, err := s.GetToken(userID)
+	token, err := s.userToken(userID)
 	if err != nil {
 		return fmt.Errorf("failed to get github token: %v", err)
 	}
@@ -300,3 +332,330 @@ func (s *GithubServiceImpl) DeleteFile(userID int, repoURL string, remotePath st
 
 	return nil
 }
+
+// DownloadFile 从GitHub仓库下载文件内容
+func (s *GithubServiceImpl) DownloadFile(userID int, repoURL string, remotePath string) ([]byte, error) {
+	reader, err := s.OpenFile(userID, repoURL, remotePath)
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	return io.ReadAll(reader)
+}
+
+// OpenFile 打开GitHub仓库中的文件，调用方负责关闭
+func (s *GithubServiceImpl) OpenFile(userID int, repoURL string, remotePath string) (io.ReadCloser, error) {
+	// 从URL中提取owner和repo名称
+	parts := strings.Split(strings.TrimSuffix(repoURL, "/"), "/")
+	owner := parts[len(parts)-2]
+	repo := parts[len(parts)-1]
+
+	// 获取token
+	token, err := s.userToken(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get github token: %v", err)
+	}
+
+	client := s.getClient(token)
+	ctx := context.Background()
+
+	reader, _, err := client.Repositories.DownloadContents(ctx, owner, repo, remotePath, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download file from GitHub: %v", err)
+	}
+
+	return reader, nil
+}
+
+// GetRepositorySize 通过GitHub API获取仓库大小，单位字节
+// GitHub 返回的 size 单位为 KB，且有一定延迟
+func (s *GithubServiceImpl) GetRepositorySize(userID int, repoURL string) (int64, error) {
+	// 从URL中提取owner和repo名称
+	parts := strings.Split(strings.TrimSuffix(repoURL, "/"), "/")
+	owner := parts[len(parts)-2]
+	repo := parts[len(parts)-1]
+
+	token, err := s.userToken(userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get github token: %v", err)
+	}
+
+	client := s.getClient(token)
+	repository, _, err := client.Repositories.Get(context.Background(), owner, repo)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get repository info: %v", err)
+	}
+
+	return int64(repository.GetSize()) * 1024, nil
+}
+
+// CreateRepository 在用户账号或组织下创建空的GitHub仓库，org 为空时创建在用户账号下
+func (s *GithubServiceImpl) CreateRepository(token string, org string, name string, description string, private bool) (*github.Repository, error) {
+	client := s.getClient(token)
+
+	repository, _, err := client.Repositories.Create(context.Background(), org, &github.Repository{
+		Name:        github.String(name),
+		Description: github.String(description),
+		Private:     github.Bool(private),
+		AutoInit:    github.Bool(false),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create repository on GitHub: %v", err)
+	}
+
+	return repository, nil
+}
+
+// DeleteRepository 删除仓库，需要 token 具有 delete_repo 权限
+func (s *GithubServiceImpl) DeleteRepository(token string, owner string, name string) error {
+	client := s.getClient(token)
+
+	if _, err := client.Repositories.Delete(context.Background(), owner, name); err != nil {
+		return fmt.Errorf("failed to delete repository on GitHub: %v", err)
+	}
+
+	return nil
+}
+
+// GetFileContent 读取仓库中的小文件及其 blob SHA，文件不存在时返回 ErrGithubFileNotFound
+// 内容通过 Contents API 内联返回，只适用于 1MB 以下的文件
+func (s *GithubServiceImpl) GetFileContent(userID int, repoURL string, remotePath string) ([]byte, string, error) {
+	owner, repo := splitRepoURL(repoURL)
+
+	token, err := s.userToken(userID)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get github token: %v", err)
+	}
+
+	client := s.getClient(token)
+	content, _, resp, err := client.Repositories.GetContents(context.Background(), owner, repo, remotePath, nil)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return nil, "", ErrGithubFileNotFound
+		}
+		return nil, "", fmt.Errorf("failed to get file info: %v", err)
+	}
+	if content == nil {
+		return nil, "", fmt.Errorf("%s is not a file", remotePath)
+	}
+
+	decoded, err := content.GetContent()
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to decode %s: %v", remotePath, err)
+	}
+	return []byte(decoded), content.GetSHA(), nil
+}
+
+// GetFileSHA 获取仓库中文件当前的 blob SHA
+func (s *GithubServiceImpl) GetFileSHA(userID int, repoURL string, remotePath string) (string, error) {
+	owner, repo := splitRepoURL(repoURL)
+
+	token, err := s.userToken(userID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get github token: %v", err)
+	}
+
+	client := s.getClient(token)
+	content, _, _, err := client.Repositories.GetContents(context.Background(), owner, repo, remotePath, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to get file info: %v", err)
+	}
+	if content == nil {
+		return "", fmt.Errorf("%s is not a file", remotePath)
+	}
+
+	return content.GetSHA(), nil
+}
+
+// UpdateFile 覆盖仓库中已有的文件，sha 为文件当前的 blob SHA，返回新的 blob SHA
+func (s *GithubServiceImpl) UpdateFile(userID int, repoURL string, remotePath string, content []byte, message string, sha string) (string, error) {
+	owner, repo := splitRepoURL(repoURL)
+
+	token, err := s.userToken(userID)
+	if err != nil {
+		return "", fmt.Errorf("failed to get github token: %v", err)
+	}
+
+	client := s.getClient(token)
+	result, _, err := client.Repositories.UpdateFile(context.Background(), owner, repo, remotePath, &github.RepositoryContentFileOptions{
+		Message: github.String(message),
+		Content: content,
+		SHA:     github.String(sha),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to update file on GitHub: %v", err)
+	}
+
+	return result.GetContent().GetSHA(), nil
+}
+
+// GetBlob 按 blob SHA 读取仓库历史中的文件内容，文件被覆盖后仍可读取
+func (s *GithubServiceImpl) GetBlob(userID int, repoURL string, sha string) ([]byte, error) {
+	owner, repo := splitRepoURL(repoURL)
+
+	token, err := s.userToken(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get github token: %v", err)
+	}
+
+	client := s.getClient(token)
+	content, _, err := client.Git.GetBlobRaw(context.Background(), owner, repo, sha)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get blob %s: %v", sha, err)
+	}
+
+	return content, nil
+}
+
+// GetOrCreateRelease 获取指定 tag 的 release，不存在时创建
+func (s *GithubServiceImpl) GetOrCreateRelease(userID int, repoURL string, tag string) (*github.RepositoryRelease, error) {
+	owner, repo := splitRepoURL(repoURL)
+
+	token, err := s.userToken(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get github token: %v", err)
+	}
+
+	client := s.getClient(token)
+	ctx := context.Background()
+
+	release, resp, err := client.Repositories.GetReleaseByTag(ctx, owner, repo, tag)
+	if err == nil {
+		return release, nil
+	}
+	if resp == nil || resp.StatusCode != http.StatusNotFound {
+		return nil, fmt.Errorf("failed to get release %s: %v", tag, err)
+	}
+
+	release, _, err = client.Repositories.CreateRelease(ctx, owner, repo, &github.RepositoryRelease{
+		TagName: github.String(tag),
+		Name:    github.String(tag),
+		Body:    github.String("Large files uploaded by PicHub"),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create release %s: %v", tag, err)
+	}
+
+	return release, nil
+}
+
+// UploadReleaseAsset 上传 release 附件，同名附件已存在时先删除
+func (s *GithubServiceImpl) UploadReleaseAsset(userID int, repoURL string, releaseID int64, name string, content []byte) (*github.ReleaseAsset, error) {
+	owner, repo := splitRepoURL(repoURL)
+
+	token, err := s.userToken(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get github token: %v", err)
+	}
+
+	client := s.getClient(token)
+	ctx := context.Background()
+
+	// release 内附件名唯一
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		assets, resp, err := client.Repositories.ListReleaseAssets(ctx, owner, repo, releaseID, opts)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list release assets: %v", err)
+		}
+		for _, asset := range assets {
+			if asset.GetName() == name {
+				if _, err := client.Repositories.DeleteReleaseAsset(ctx, owner, repo, asset.GetID()); err != nil {
+					return nil, fmt.Errorf("failed to replace release asset %s: %v", name, err)
+				}
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+
+	// go-github 只支持从 *os.File 上传附件，先写入临时文件
+	tmp, err := os.CreateTemp("", "pichub-asset-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	if _, err := tmp.Write(content); err != nil {
+		return nil, err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	asset, _, err := client.Repositories.UploadReleaseAsset(ctx, owner, repo, releaseID, &github.UploadOptions{Name: name}, tmp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload release asset: %v", err)
+	}
+
+	return asset, nil
+}
+
+// DeleteReleaseAsset 删除 release 附件
+func (s *GithubServiceImpl) DeleteReleaseAsset(userID int, repoURL string, assetID int64) error {
+	owner, repo := splitRepoURL(repoURL)
+
+	token, err := s.userToken(userID)
+	if err != nil {
+		return fmt.Errorf("failed to get github token: %v", err)
+	}
+
+	client := s.getClient(token)
+	if _, err := client.Repositories.DeleteReleaseAsset(context.Background(), owner, repo, assetID); err != nil {
+		return fmt.Errorf("failed to delete release asset: %v", err)
+	}
+
+	return nil
+}
+
+// DownloadReleaseAsset 下载 release 附件内容
+func (s *GithubServiceImpl) DownloadReleaseAsset(userID int, repoURL string, assetID int64) ([]byte, error) {
+	rc, err := s.OpenReleaseAsset(userID, repoURL, assetID)
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	return io.ReadAll(rc)
+}
+
+// OpenReleaseAsset 打开 release 附件，调用方负责关闭
+func (s *GithubServiceImpl) OpenReleaseAsset(userID int, repoURL string, assetID int64) (io.ReadCloser, error) {
+	owner, repo := splitRepoURL(repoURL)
+
+	token, err := s.userToken(userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get github token: %v", err)
+	}
+
+	client := s.getClient(token)
+	rc, _, err := client.Repositories.DownloadReleaseAsset(context.Background(), owner, repo, assetID, http.DefaultClient)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download release asset: %v", err)
+	}
+
+	return rc, nil
+}
+
+// userToken 获取用户的 GitHub token
+func (s *GithubServiceImpl) userToken(userID int) (string, error) {
+	if s.tokenSource != nil {
+		return s.tokenSource(userID)
+	}
+	return ConfigService.GetGithubToken(userID)
+}
+
+// githubAPIURL 自定义的 GitHub 地址，用于 GitHub Enterprise 或测试用的模拟服务，为空时使用 github.com
+func githubAPIURL() string {
+	return strings.TrimSuffix(viper.GetString("GITHUB_API_URL"), "/")
+}
+
+// splitRepoURL 从仓库URL中提取owner和repo名称
+func splitRepoURL(repoURL string) (string, string) {
+	parts := strings.Split(strings.TrimSuffix(repoURL, "/"), "/")
+	return parts[len(parts)-2], parts[len(parts)-1]
+}
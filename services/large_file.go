@@ -0,0 +1,320 @@
+package services
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"pichub.api/constants"
+	"pichub.api/infra/logger"
+	"pichub.api/models"
+	"pichub.api/pkg/utils"
+)
+
+type LargeFileServiceImpl struct {
+	client *http.Client
+}
+
+var LargeFileService = &LargeFileServiceImpl{
+	client: &http.Client{Timeout: 10 * time.Minute},
+}
+
+// lfsAttributesLine LFS 目录在 .gitattributes 中的跟踪规则
+var lfsAttributesLine = constants.LargeFileLFSDir + "/** filter=lfs diff=lfs merge=lfs -text"
+
+// lfsBatchRequest Git LFS batch API 请求
+type lfsBatchRequest struct {
+	Operation string      `json:"operation"`
+	Transfers []string    `json:"transfers"`
+	Objects   []lfsObject `json:"objects"`
+}
+
+type lfsObject struct {
+	Oid     string               `json:"oid"`
+	Size    int64                `json:"size"`
+	Actions map[string]lfsAction `json:"actions,omitempty"`
+	Error   *struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+type lfsAction struct {
+	Href   string            `json:"href"`
+	Header map[string]string `json:"header"`
+}
+
+type lfsBatchResponse struct {
+	Objects []lfsObject `json:"objects"`
+}
+
+// StorageFor 根据文件大小和用户配置决定文件的存储位置
+// 阈值和方式分别通过 file.large_file_threshold、file.large_file_mode 配置
+func (s *LargeFileServiceImpl) StorageFor(userID int, size int64) string {
+	threshold := int64(utils.ToInt(s.config("large_file_threshold", userID), constants.DefaultLargeFileThreshold))
+	if threshold <= 0 || size <= threshold {
+		return models.FileStorageContents
+	}
+
+	if utils.ToString(s.config("large_file_mode", userID)) == constants.LargeFileModeLFS {
+		return models.FileStorageLFS
+	}
+	return models.FileStorageRelease
+}
+
+// Upload 按存储位置上传大文件，并补充文件记录的存储信息
+func (s *LargeFileServiceImpl) Upload(repo *models.Repository, file *models.File, storageType string, content []byte) error {
+	switch storageType {
+	case models.FileStorageRelease:
+		return s.uploadRelease(repo, file, content)
+	case models.FileStorageLFS:
+		return s.uploadLFS(repo, file, content)
+	}
+	return fmt.Errorf("unsupported storage type: %s", storageType)
+}
+
+// Delete 删除大文件
+// LFS 对象无法通过 API 删除，只删除仓库中的指针文件
+func (s *LargeFileServiceImpl) Delete(repo *models.Repository, file *models.File) error {
+	switch file.StorageType {
+	case models.FileStorageRelease:
+		assetID, err := strconv.ParseInt(file.StorageRef, 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid release asset id: %s", file.StorageRef)
+		}
+		return GithubService.DeleteReleaseAsset(repo.UserID, repo.RepoURL, assetID)
+	case models.FileStorageLFS:
+		return GithubService.DeleteFile(repo.UserID, repo.RepoURL, file.URL)
+	}
+	return fmt.Errorf("unsupported storage type: %s", file.StorageType)
+}
+
+// Open 打开大文件内容，调用方负责关闭
+func (s *LargeFileServiceImpl) Open(repo *models.Repository, file *models.File) (io.ReadCloser, error) {
+	switch file.StorageType {
+	case models.FileStorageRelease:
+		assetID, err := strconv.ParseInt(file.StorageRef, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid release asset id: %s", file.StorageRef)
+		}
+		return GithubService.OpenReleaseAsset(repo.UserID, repo.RepoURL, assetID)
+	case models.FileStorageLFS:
+		return s.openLFS(repo, file)
+	}
+	return nil, fmt.Errorf("unsupported storage type: %s", file.StorageType)
+}
+
+func (s *LargeFileServiceImpl) uploadRelease(repo *models.Repository, file *models.File, content []byte) error {
+	release, err := GithubService.GetOrCreateRelease(repo.UserID, repo.RepoURL, constants.LargeFileReleaseTag)
+	if err != nil {
+		return err
+	}
+
+	asset, err := GithubService.UploadReleaseAsset(repo.UserID, repo.RepoURL, release.GetID(), file.Filename, content)
+	if err != nil {
+		return err
+	}
+
+	file.StorageType = models.FileStorageRelease
+	file.StorageRef = strconv.FormatInt(asset.GetID(), 10)
+	file.RemoteURL = asset.GetBrowserDownloadURL()
+	return nil
+}
+
+func (s *LargeFileServiceImpl) uploadLFS(repo *models.Repository, file *models.File, content []byte) error {
+	sum := sha256.Sum256(content)
+	oid := hex.EncodeToString(sum[:])
+	size := int64(len(content))
+
+	if err := s.ensureLFSAttributes(repo); err != nil {
+		return err
+	}
+
+	object, err := s.lfsBatch(repo, "upload", oid, size)
+	if err != nil {
+		return err
+	}
+
+	// 没有 upload 动作说明服务端已有该对象
+	if upload, ok := object.Actions["upload"]; ok {
+		if err := s.lfsTransfer(http.MethodPut, upload, bytes.NewReader(content)); err != nil {
+			return fmt.Errorf("lfs upload failed: %v", err)
+		}
+		if verify, ok := object.Actions["verify"]; ok {
+			body, _ := json.Marshal(lfsObject{Oid: oid, Size: size})
+			if err := s.lfsTransfer(http.MethodPost, verify, bytes.NewReader(body)); err != nil {
+				return fmt.Errorf("lfs verify failed: %v", err)
+			}
+		}
+	}
+
+	// 提交指针文件，放在 LFS 目录下由 .gitattributes 跟踪
+	file.URL = constants.LargeFileLFSDir + "/" + file.URL
+	pointer := fmt.Sprintf("version https://git-lfs.github.com/spec/v1\noid sha256:%s\nsize %d\n", oid, size)
+	if err := GithubService.CommitFile(repo.UserID, repo.RepoURL, file.URL, []byte(pointer), fmt.Sprintf("Upload large file: %s", file.Filename)); err != nil {
+		return err
+	}
+
+	owner, name := splitRepoURL(repo.RepoURL)
+	file.StorageType = models.FileStorageLFS
+	file.StorageRef = oid
+	file.RemoteURL = fmt.Sprintf("https://media.githubusercontent.com/media/%s/%s/%s/%s", owner, name, repo.RepoBranch, file.URL)
+	return nil
+}
+
+func (s *LargeFileServiceImpl) openLFS(repo *models.Repository, file *models.File) (io.ReadCloser, error) {
+	object, err := s.lfsBatch(repo, "download", file.StorageRef, int64(file.Filesize))
+	if err != nil {
+		return nil, err
+	}
+
+	download, ok := object.Actions["download"]
+	if !ok {
+		return nil, fmt.Errorf("lfs object %s has no download action", file.StorageRef)
+	}
+
+	req, err := http.NewRequest(http.MethodGet, download.Href, nil)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range download.Header {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("lfs download failed: %v", err)
+	}
+	if resp.StatusCode >= 300 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("lfs download failed: unexpected status %s", resp.Status)
+	}
+	return resp.Body, nil
+}
+
+// lfsBatch 调用仓库的 LFS batch API，返回单个对象的传输动作
+func (s *LargeFileServiceImpl) lfsBatch(repo *models.Repository, operation string, oid string, size int64) (*lfsObject, error) {
+	token, err := GithubService.userToken(repo.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get github token: %v", err)
+	}
+
+	body, _ := json.Marshal(lfsBatchRequest{
+		Operation: operation,
+		Transfers: []string{"basic"},
+		Objects:   []lfsObject{{Oid: oid, Size: size}},
+	})
+
+	req, err := http.NewRequest(http.MethodPost, s.lfsEndpoint(repo), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.git-lfs+json")
+	req.Header.Set("Content-Type", "application/vnd.git-lfs+json")
+	req.SetBasicAuth("x-access-token", token)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("lfs batch request failed: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("lfs batch request failed: %s %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	var result lfsBatchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("invalid lfs batch response: %v", err)
+	}
+	if len(result.Objects) == 0 {
+		return nil, fmt.Errorf("lfs batch response has no objects")
+	}
+
+	object := result.Objects[0]
+	if object.Error != nil {
+		return nil, fmt.Errorf("lfs object error %d: %s", object.Error.Code, object.Error.Message)
+	}
+	return &object, nil
+}
+
+// lfsTransfer 按 batch API 返回的动作上传数据
+func (s *LargeFileServiceImpl) lfsTransfer(method string, action lfsAction, body io.Reader) error {
+	req, err := http.NewRequest(method, action.Href, body)
+	if err != nil {
+		return err
+	}
+	for key, value := range action.Header {
+		req.Header.Set(key, value)
+	}
+	if method == http.MethodPut {
+		req.Header.Set("Content-Type", "application/octet-stream")
+	} else if method == http.MethodPost {
+		req.Header.Set("Content-Type", "application/vnd.git-lfs+json")
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return nil
+}
+
+// lfsEndpoint 仓库的 LFS batch API 地址，配置了 GITHUB_API_URL 时使用同一个地址，便于测试
+func (s *LargeFileServiceImpl) lfsEndpoint(repo *models.Repository) string {
+	if apiURL := githubAPIURL(); apiURL != "" {
+		owner, name := splitRepoURL(repo.RepoURL)
+		return fmt.Sprintf("%s/%s/%s.git/info/lfs/objects/batch", apiURL, owner, name)
+	}
+	return strings.TrimSuffix(repo.RepoURL, "/") + ".git/info/lfs/objects/batch"
+}
+
+// ensureLFSAttributes 确保仓库的 .gitattributes 跟踪 LFS 目录
+func (s *LargeFileServiceImpl) ensureLFSAttributes(repo *models.Repository) error {
+	content, sha, err := GithubService.GetFileContent(repo.UserID, repo.RepoURL, ".gitattributes")
+	if errors.Is(err, ErrGithubFileNotFound) {
+		logger.Infof("create .gitattributes for repository %s", repo.RepoURL)
+		return GithubService.CommitFile(repo.UserID, repo.RepoURL, ".gitattributes", []byte(lfsAttributesLine+"\n"), "Track large files with Git LFS")
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, line := range strings.Split(string(content), "\n") {
+		if strings.TrimSpace(line) == lfsAttributesLine {
+			return nil
+		}
+	}
+
+	updated := string(content)
+	if updated != "" && !strings.HasSuffix(updated, "\n") {
+		updated += "\n"
+	}
+	updated += lfsAttributesLine + "\n"
+
+	_, err = GithubService.UpdateFile(repo.UserID, repo.RepoURL, ".gitattributes", []byte(updated), "Track large files with Git LFS", sha)
+	return err
+}
+
+// config 获取大文件配置，用户未配置时使用系统配置
+func (s *LargeFileServiceImpl) config(name string, userID int) interface{} {
+	value, err := ConfigService.Get("file", name, userID)
+	if err != nil || utils.IsEmpty(value) {
+		value, _ = ConfigService.Get("file", name, 0)
+	}
+	return value
+}
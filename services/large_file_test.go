@@ -0,0 +1,351 @@
+package services
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/spf13/viper"
+	"pichub.api/constants"
+	"pichub.api/models"
+	"pichub.api/pkg/utils"
+)
+
+// fakeGithub 模拟 GitHub 的 Contents、Release 和 LFS 接口，数据保存在内存中
+type fakeGithub struct {
+	mu sync.Mutex
+	*httptest.Server
+
+	files      map[string][]byte
+	release    bool
+	assets     map[int64][]byte
+	assetNames map[int64]string
+	nextAsset  int64
+	lfs        map[string][]byte
+	verified   map[string]bool
+
+	// contentsStatus 不为 0 时 Contents 接口直接返回该状态码
+	contentsStatus int
+}
+
+func newFakeGithub(t *testing.T) *fakeGithub {
+	f := &fakeGithub{
+		files:      map[string][]byte{},
+		assets:     map[int64][]byte{},
+		assetNames: map[int64]string{},
+		nextAsset:  100,
+		lfs:        map[string][]byte{},
+		verified:   map[string]bool{},
+	}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(f.Close)
+
+	viper.Set("GITHUB_API_URL", f.URL)
+	t.Cleanup(func() { viper.Set("GITHUB_API_URL", "") })
+
+	GithubService.tokenSource = func(userID int) (string, error) { return "test-token", nil }
+	t.Cleanup(func() { GithubService.tokenSource = nil })
+	return f
+}
+
+func (f *fakeGithub) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	path := r.URL.Path
+	switch {
+	case strings.HasPrefix(path, "/api/v3/repos/o/r/contents/"):
+		f.serveContents(w, r, strings.TrimPrefix(path, "/api/v3/repos/o/r/contents/"))
+	case path == "/api/v3/repos/o/r/releases/tags/"+constants.LargeFileReleaseTag:
+		if !f.release {
+			writeJSON(w, http.StatusNotFound, map[string]string{"message": "Not Found"})
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{"id": 1, "tag_name": constants.LargeFileReleaseTag})
+	case path == "/api/v3/repos/o/r/releases" && r.Method == http.MethodPost:
+		f.release = true
+		writeJSON(w, http.StatusCreated, map[string]interface{}{"id": 1, "tag_name": constants.LargeFileReleaseTag})
+	case path == "/api/v3/repos/o/r/releases/1/assets":
+		list := []map[string]interface{}{}
+		for id, name := range f.assetNames {
+			list = append(list, map[string]interface{}{"id": id, "name": name})
+		}
+		writeJSON(w, http.StatusOK, list)
+	case path == "/api/uploads/repos/o/r/releases/1/assets":
+		content, _ := io.ReadAll(r.Body)
+		f.nextAsset++
+		f.assets[f.nextAsset] = content
+		f.assetNames[f.nextAsset] = r.URL.Query().Get("name")
+		writeJSON(w, http.StatusCreated, map[string]interface{}{
+			"id":                   f.nextAsset,
+			"name":                 r.URL.Query().Get("name"),
+			"browser_download_url": fmt.Sprintf("%s/o/r/releases/download/%s/%s", f.URL, constants.LargeFileReleaseTag, r.URL.Query().Get("name")),
+		})
+	case strings.HasPrefix(path, "/api/v3/repos/o/r/releases/assets/"):
+		id, _ := strconv.ParseInt(strings.TrimPrefix(path, "/api/v3/repos/o/r/releases/assets/"), 10, 64)
+		content, ok := f.assets[id]
+		if !ok {
+			writeJSON(w, http.StatusNotFound, map[string]string{"message": "Not Found"})
+			return
+		}
+		if r.Method == http.MethodDelete {
+			delete(f.assets, id)
+			delete(f.assetNames, id)
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write(content)
+	case path == "/o/r.git/info/lfs/objects/batch":
+		f.serveBatch(w, r)
+	case strings.HasPrefix(path, "/lfs/objects/"):
+		oid := strings.TrimPrefix(path, "/lfs/objects/")
+		if r.Header.Get("X-Lfs-Token") != "lfs-"+oid {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Method == http.MethodPut {
+			f.lfs[oid], _ = io.ReadAll(r.Body)
+			return
+		}
+		content, ok := f.lfs[oid]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(content)
+	case strings.HasPrefix(path, "/raw/"):
+		content, ok := f.files[strings.TrimPrefix(path, "/raw/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(content)
+	case path == "/lfs/verify":
+		var object lfsObject
+		json.NewDecoder(r.Body).Decode(&object)
+		if int64(len(f.lfs[object.Oid])) != object.Size {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			return
+		}
+		f.verified[object.Oid] = true
+	default:
+		writeJSON(w, http.StatusNotFound, map[string]string{"message": "Not Found"})
+	}
+}
+
+func (f *fakeGithub) serveContents(w http.ResponseWriter, r *http.Request, name string) {
+	if f.contentsStatus != 0 {
+		writeJSON(w, f.contentsStatus, map[string]string{"message": http.StatusText(f.contentsStatus)})
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		content, ok := f.files[name]
+		if !ok {
+			f.serveDir(w, name)
+			return
+		}
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"type":     "file",
+			"path":     name,
+			"encoding": "base64",
+			"content":  base64.StdEncoding.EncodeToString(content),
+			"sha":      blobSHA(content),
+		})
+	case http.MethodPut:
+		var opts struct {
+			Content []byte `json:"content"`
+			SHA     string `json:"sha"`
+		}
+		json.NewDecoder(r.Body).Decode(&opts)
+		old, ok := f.files[name]
+		if ok && opts.SHA == "" {
+			writeJSON(w, http.StatusUnprocessableEntity, map[string]string{"message": `Invalid request. "sha" wasn't supplied.`})
+			return
+		}
+		if ok && opts.SHA != blobSHA(old) {
+			writeJSON(w, http.StatusConflict, map[string]string{"message": "sha mismatch"})
+			return
+		}
+		f.files[name] = opts.Content
+		writeJSON(w, http.StatusOK, map[string]interface{}{"content": map[string]string{"sha": blobSHA(opts.Content)}})
+	case http.MethodDelete:
+		delete(f.files, name)
+		writeJSON(w, http.StatusOK, map[string]interface{}{})
+	}
+}
+
+// serveDir 列出目录下的文件，DownloadContents 通过目录列表中的 download_url 下载
+func (f *fakeGithub) serveDir(w http.ResponseWriter, dir string) {
+	list := []map[string]interface{}{}
+	for name := range f.files {
+		if path.Dir(name) == dir {
+			list = append(list, map[string]interface{}{
+				"type":         "file",
+				"name":         path.Base(name),
+				"path":         name,
+				"download_url": f.URL + "/raw/" + name,
+			})
+		}
+	}
+	if len(list) == 0 {
+		writeJSON(w, http.StatusNotFound, map[string]string{"message": "Not Found"})
+		return
+	}
+	writeJSON(w, http.StatusOK, list)
+}
+
+func (f *fakeGithub) serveBatch(w http.ResponseWriter, r *http.Request) {
+	if user, pass, ok := r.BasicAuth(); !ok || user != "x-access-token" || pass != "test-token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	var req lfsBatchRequest
+	json.NewDecoder(r.Body).Decode(&req)
+	object := req.Objects[0]
+	header := map[string]string{"X-Lfs-Token": "lfs-" + object.Oid}
+	object.Actions = map[string]lfsAction{}
+
+	switch req.Operation {
+	case "upload":
+		if _, ok := f.lfs[object.Oid]; !ok {
+			object.Actions["upload"] = lfsAction{Href: f.URL + "/lfs/objects/" + object.Oid, Header: header}
+			object.Actions["verify"] = lfsAction{Href: f.URL + "/lfs/verify"}
+		}
+	case "download":
+		object.Actions["download"] = lfsAction{Href: f.URL + "/lfs/objects/" + object.Oid, Header: header}
+	}
+	writeJSON(w, http.StatusOK, lfsBatchResponse{Objects: []lfsObject{object}})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// blobSHA 与 GitHub 一致，按 Git blob 对象计算 SHA
+func blobSHA(content []byte) string {
+	sha, _ := utils.CalculateGitHash(bytes.NewReader(content), int64(len(content)))
+	return sha
+}
+
+func testRepository() *models.Repository {
+	return &models.Repository{UserID: 1, RepoURL: "https://github.com/o/r", RepoBranch: "main"}
+}
+
+func readAllAndClose(t *testing.T, rc io.ReadCloser) []byte {
+	t.Helper()
+	defer rc.Close()
+	content, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	return content
+}
+
+func TestLargeFileRelease(t *testing.T) {
+	f := newFakeGithub(t)
+	repo := testRepository()
+	content := bytes.Repeat([]byte("release"), 1024)
+	file := &models.File{Filename: "big.bin", URL: "big.bin", Filesize: uint(len(content))}
+
+	if err := LargeFileService.Upload(repo, file, models.FileStorageRelease, content); err != nil {
+		t.Fatalf("upload: %v", err)
+	}
+	if !f.release {
+		t.Fatal("release was not created")
+	}
+	if file.StorageType != models.FileStorageRelease || file.StorageRef == "" {
+		t.Fatalf("unexpected storage info: %s %s", file.StorageType, file.StorageRef)
+	}
+
+	rc, err := LargeFileService.Open(repo, file)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if got := readAllAndClose(t, rc); !bytes.Equal(got, content) {
+		t.Fatalf("downloaded %d bytes, want %d", len(got), len(content))
+	}
+
+	if err := LargeFileService.Delete(repo, file); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if len(f.assets) != 0 {
+		t.Fatalf("asset still exists after delete")
+	}
+}
+
+func TestLargeFileLFS(t *testing.T) {
+	f := newFakeGithub(t)
+	repo := testRepository()
+	content := bytes.Repeat([]byte("lfs"), 2048)
+	file := &models.File{Filename: "big.bin", URL: "big.bin", Filesize: uint(len(content))}
+
+	if err := LargeFileService.Upload(repo, file, models.FileStorageLFS, content); err != nil {
+		t.Fatalf("upload: %v", err)
+	}
+	if got := string(f.files[".gitattributes"]); got != lfsAttributesLine+"\n" {
+		t.Fatalf(".gitattributes = %q", got)
+	}
+	if !f.verified[file.StorageRef] {
+		t.Fatal("lfs object was not verified")
+	}
+	if file.URL != constants.LargeFileLFSDir+"/big.bin" {
+		t.Fatalf("pointer path = %s", file.URL)
+	}
+	if pointer := string(f.files[file.URL]); !strings.Contains(pointer, "oid sha256:"+file.StorageRef) {
+		t.Fatalf("unexpected pointer file: %q", pointer)
+	}
+
+	rc, err := LargeFileService.Open(repo, file)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	if got := readAllAndClose(t, rc); !bytes.Equal(got, content) {
+		t.Fatalf("downloaded %d bytes, want %d", len(got), len(content))
+	}
+
+	if err := LargeFileService.Delete(repo, file); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, ok := f.files[file.URL]; ok {
+		t.Fatal("pointer file still exists after delete")
+	}
+}
+
+func TestEnsureLFSAttributesAppends(t *testing.T) {
+	f := newFakeGithub(t)
+	f.files[".gitattributes"] = []byte("*.psd binary")
+
+	if err := LargeFileService.ensureLFSAttributes(testRepository()); err != nil {
+		t.Fatalf("ensure: %v", err)
+	}
+	if got, want := string(f.files[".gitattributes"]), "*.psd binary\n"+lfsAttributesLine+"\n"; got != want {
+		t.Fatalf(".gitattributes = %q, want %q", got, want)
+	}
+}
+
+func TestEnsureLFSAttributesOnlyCreatesOnNotFound(t *testing.T) {
+	f := newFakeGithub(t)
+	f.contentsStatus = http.StatusInternalServerError
+
+	if err := LargeFileService.ensureLFSAttributes(testRepository()); err == nil {
+		t.Fatal("expected error when the contents API fails")
+	}
+	if _, ok := f.files[".gitattributes"]; ok {
+		t.Fatal(".gitattributes must not be overwritten when the lookup fails")
+	}
+}
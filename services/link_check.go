@@ -0,0 +1,251 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"pichub.api/constants"
+	"pichub.api/infra/database"
+	"pichub.api/infra/logger"
+	"pichub.api/models"
+)
+
+type LinkCheckServiceImpl struct {
+	// 地址模板由用户填写，连接时拒绝内网地址
+	client *http.Client
+	// 正在运行检查的用户，0 为定时任务的全量检查，同一用户同时只运行一个检查
+	running sync.Map
+}
+
+var LinkCheckService = &LinkCheckServiceImpl{
+	client: newOutboundClient(constants.LinkCheckTimeoutSecond * time.Second),
+}
+
+// ErrLinkCheckRunning 已有检查任务在运行
+var ErrLinkCheckRunning = errors.New("link check is already running")
+
+// linkTarget 待检查的地址
+type linkTarget struct {
+	file *models.File
+	url  string
+	kind string
+}
+
+// Run 检查文件的访问地址，userID 为 0 时检查所有用户的文件
+func (s *LinkCheckServiceImpl) Run(userID int) error {
+	if _, loaded := s.running.LoadOrStore(userID, true); loaded {
+		return ErrLinkCheckRunning
+	}
+	defer s.running.Delete(userID)
+
+	return s.run(userID)
+}
+
+// Start 在后台检查用户文件的访问地址，该用户已有检查在运行时返回 ErrLinkCheckRunning
+func (s *LinkCheckServiceImpl) Start(userID int) error {
+	if _, loaded := s.running.LoadOrStore(userID, true); loaded {
+		return ErrLinkCheckRunning
+	}
+
+	go func() {
+		defer s.running.Delete(userID)
+		if err := s.run(userID); err != nil {
+			logger.Errorf("link check of user %d failed: %v", userID, err)
+		}
+	}()
+	return nil
+}
+
+func (s *LinkCheckServiceImpl) run(userID int) error {
+	concurrency := viper.GetInt("LINK_CHECK_CONCURRENCY")
+	if concurrency <= 0 {
+		concurrency = constants.DefaultLinkCheckConcurrency
+	}
+
+	start := time.Now()
+	checked := 0
+	lastID := 0
+	for {
+		query := database.DB.Where("id > ?", lastID)
+		if userID > 0 {
+			query = query.Where("user_id = ?", userID)
+		}
+
+		var files []models.File
+		if err := query.Order("id ASC").Limit(constants.LinkCheckBatch).Find(&files).Error; err != nil {
+			return err
+		}
+		if len(files) == 0 {
+			break
+		}
+		lastID = files[len(files)-1].ID
+
+		var targets []linkTarget
+		for i := range files {
+			targets = append(targets, s.targets(&files[i])...)
+		}
+		s.checkTargets(targets, concurrency)
+		checked += len(targets)
+	}
+
+	// 清理已删除文件和已不再使用的地址的检查记录
+	query := database.DB.Where("checked_at < ?", start)
+	if userID > 0 {
+		query = query.Where("user_id = ?", userID)
+	}
+	if err := query.Delete(&models.FileLinkCheck{}).Error; err != nil {
+		return err
+	}
+
+	logger.Infof("link check finished, %d links checked in %s", checked, time.Since(start))
+	return nil
+}
+
+// Report 获取用户的链接检查汇总和失效链接列表
+func (s *LinkCheckServiceImpl) Report(userID int, page int, pageSize int) (*models.LinkReportSummary, []models.BrokenLinkItem, error) {
+	summary := &models.LinkReportSummary{}
+	base := database.DB.Model(&models.FileLinkCheck{}).Where("user_id = ?", userID)
+
+	if err := base.Session(&gorm.Session{}).Count(&summary.CheckedLinks).Error; err != nil {
+		return nil, nil, err
+	}
+	broken := base.Session(&gorm.Session{}).Where("broken = ?", true)
+	if err := broken.Session(&gorm.Session{}).Count(&summary.BrokenLinks).Error; err != nil {
+		return nil, nil, err
+	}
+	if err := broken.Session(&gorm.Session{}).Distinct("file_id").Count(&summary.BrokenFiles).Error; err != nil {
+		return nil, nil, err
+	}
+
+	var last models.FileLinkCheck
+	if err := base.Session(&gorm.Session{}).Order("checked_at DESC").Limit(1).Find(&last).Error; err != nil {
+		return nil, nil, err
+	}
+	if last.ID != 0 {
+		summary.LastCheckedAt = &last.CheckedAt
+	}
+
+	items := []models.BrokenLinkItem{}
+	checkTable := database.DB.NamingStrategy.TableName("FileLinkCheck")
+	if err := database.DB.Table(checkTable+" c").
+		Select("c.*, f.filename, f.raw_filename, f.repo_id").
+		Joins(fmt.Sprintf("JOIN %s f ON f.id = c.file_id", fileTable())).
+		Where("c.user_id = ? AND c.broken = ?", userID, true).
+		Order("c.file_id DESC, c.id ASC").
+		Offset((page - 1) * pageSize).Limit(pageSize).
+		Scan(&items).Error; err != nil {
+		return nil, nil, err
+	}
+
+	return summary, items, nil
+}
+
+// targets 生成文件需要检查的地址：主地址、备用地址以及仓库文件的 raw 地址
+// 私有仓库的文件只能通过签名地址访问，不做检查
+func (s *LinkCheckServiceImpl) targets(file *models.File) []linkTarget {
+	urls, repo := FileService.FileURLs(file)
+	if repo != nil && repo.Private {
+		return nil
+	}
+
+	seen := map[string]bool{}
+	var targets []linkTarget
+	for i, url := range urls {
+		if seen[url] {
+			continue
+		}
+		seen[url] = true
+		kind := models.LinkKindAlternate
+		if i == 0 {
+			kind = models.LinkKindPrimary
+		}
+		targets = append(targets, linkTarget{file: file, url: url, kind: kind})
+	}
+
+	if repo != nil && file.RemoteURL == "" {
+		raw := repo.RenderURL(models.URLPresetRaw, file, "")
+		if !seen[raw] {
+			targets = append(targets, linkTarget{file: file, url: raw, kind: models.LinkKindRaw})
+		}
+	}
+	return targets
+}
+
+// checkTargets 以有限的并发检查一批地址并保存结果
+func (s *LinkCheckServiceImpl) checkTargets(targets []linkTarget, concurrency int) {
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, concurrency)
+
+	for _, target := range targets {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(target linkTarget) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			result := s.check(target)
+			if err := database.DB.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "file_id"}, {Name: "url"}},
+				DoUpdates: clause.AssignmentColumns([]string{"kind", "status_code", "latency_ms", "broken", "error", "checked_at"}),
+			}).Create(result).Error; err != nil {
+				logger.Errorf("save link check of file %d failed: %v", target.file.ID, err)
+			}
+		}(target)
+	}
+	wg.Wait()
+}
+
+// check 发送 HEAD 请求检查地址，不支持 HEAD 的服务改用只取首字节的 GET
+func (s *LinkCheckServiceImpl) check(target linkTarget) *models.FileLinkCheck {
+	result := &models.FileLinkCheck{
+		FileID:    target.file.ID,
+		UserID:    target.file.UserID,
+		URL:       target.url,
+		Kind:      target.kind,
+		CheckedAt: time.Now(),
+	}
+
+	start := time.Now()
+	code, err := s.request(http.MethodHead, target.url)
+	if err == nil && (code == http.StatusMethodNotAllowed || code == http.StatusNotImplemented) {
+		code, err = s.request(http.MethodGet, target.url)
+	}
+	result.LatencyMs = time.Since(start).Milliseconds()
+	result.StatusCode = code
+
+	if err != nil {
+		result.Broken = true
+		result.Error = err.Error()
+		if len(result.Error) > 500 {
+			result.Error = result.Error[:500]
+		}
+	} else if code >= 400 {
+		result.Broken = true
+		result.Error = http.StatusText(code)
+	}
+	return result
+}
+
+func (s *LinkCheckServiceImpl) request(method string, url string) (int, error) {
+	req, err := http.NewRequest(method, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	if method == http.MethodGet {
+		req.Header.Set("Range", "bytes=0-0")
+	}
+	req.Header.Set("User-Agent", "PicHub-LinkChecker")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,45 @@
+package services
+
+import (
+	"pichub.api/infra/database"
+	"pichub.api/models"
+)
+
+type LinkTemplateServiceImpl struct{}
+
+var LinkTemplateService = &LinkTemplateServiceImpl{}
+
+// linkTemplateConfigType 链接模板在 config 表中的类型，name 为模板名称，value 为模板内容
+const linkTemplateConfigType = "link_template"
+
+// GetTemplates 获取用户的链接模板，user_id 为 0 的模板对所有用户生效，用户的同名模板覆盖系统模板
+func (s *LinkTemplateServiceImpl) GetTemplates(userID int) (map[string]string, error) {
+	// 直接读取原始值，模板内容不按 JSON 解析
+	var configs []models.Config
+	if err := database.DB.Where("type = ? AND user_id IN ?", linkTemplateConfigType, []int{0, userID}).
+		Order("user_id ASC").Find(&configs).Error; err != nil {
+		return nil, err
+	}
+
+	templates := map[string]string{}
+	for _, config := range configs {
+		if config.Name == "" || config.Value == "" {
+			continue
+		}
+		templates[config.Name] = config.Value
+	}
+	return templates, nil
+}
+
+// Render 按模板生成文件的引用代码，没有模板时返回 nil
+func (s *LinkTemplateServiceImpl) Render(templates map[string]string, file *models.FileResponse) map[string]string {
+	if len(templates) == 0 {
+		return nil
+	}
+
+	links := make(map[string]string, len(templates))
+	for name, template := range templates {
+		links[name] = file.RenderLink(template)
+	}
+	return links
+}
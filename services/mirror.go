@@ -0,0 +1,604 @@
+package services
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"pichub.api/config"
+	"pichub.api/constants"
+	"pichub.api/infra/database"
+	"pichub.api/infra/logger"
+	"pichub.api/models"
+	"pichub.api/pkg/utils"
+)
+
+type MirrorServiceImpl struct {
+	client *http.Client
+}
+
+var MirrorService = &MirrorServiceImpl{
+	client: newOutboundClient(60 * time.Second),
+}
+
+// mirrorBackend 镜像存储后端
+type mirrorBackend interface {
+	Put(remotePath string, content []byte) error
+	Delete(remotePath string) error
+	URL(remotePath string) string
+}
+
+// ErrMirrorPrivateRepo 镜像中的文件可以直接访问，私有仓库不同步到镜像
+var ErrMirrorPrivateRepo = errors.New("mirrors are not available for private repositories")
+
+// ListMirrors 获取仓库的镜像列表及副本同步统计
+func (s *MirrorServiceImpl) ListMirrors(userID int, repoID int) ([]models.MirrorStatusResponse, error) {
+	var mirrors []models.RepoMirror
+	if err := database.DB.Where("user_id = ? AND repo_id = ?", userID, repoID).Order("id ASC").Find(&mirrors).Error; err != nil {
+		return nil, err
+	}
+
+	response := []models.MirrorStatusResponse{}
+	for _, mirror := range mirrors {
+		var rows []struct {
+			Status string
+			Count  int64
+		}
+		if err := database.DB.Model(&models.FileReplica{}).
+			Select("status, COUNT(*) AS count").
+			Where("mirror_id = ?", mirror.ID).
+			Group("status").
+			Scan(&rows).Error; err != nil {
+			return nil, err
+		}
+
+		counts := map[string]int64{}
+		for _, row := range rows {
+			counts[row.Status] = row.Count
+		}
+		response = append(response, models.MirrorStatusResponse{RepoMirror: mirror, Counts: counts})
+	}
+	return response, nil
+}
+
+// GetMirror 获取镜像
+func (s *MirrorServiceImpl) GetMirror(userID int, mirrorID int) (*models.RepoMirror, error) {
+	var mirror models.RepoMirror
+	if err := database.DB.Where("id = ? AND user_id = ?", mirrorID, userID).First(&mirror).Error; err != nil {
+		return nil, err
+	}
+	return &mirror, nil
+}
+
+// CreateMirror 为仓库添加镜像，已有文件由补偿任务同步
+func (s *MirrorServiceImpl) CreateMirror(userID int, repoID int, req models.RepoMirrorRequest) (*models.RepoMirror, error) {
+	repo, err := RepositoryService.GetRepository(userID, repoID)
+	if err != nil {
+		return nil, errors.New("repository not found")
+	}
+	if repo.Private {
+		return nil, ErrMirrorPrivateRepo
+	}
+
+	mirror := &models.RepoMirror{UserID: userID, RepoID: repoID, Enabled: true}
+	if err := s.fillMirror(mirror, req); err != nil {
+		return nil, err
+	}
+	if err := s.validateMirror(mirror); err != nil {
+		return nil, err
+	}
+
+	if err := database.DB.Create(mirror).Error; err != nil {
+		return nil, err
+	}
+	return mirror, nil
+}
+
+// UpdateMirror 更新镜像设置，密码留空时保持不变
+func (s *MirrorServiceImpl) UpdateMirror(userID int, mirrorID int, req models.RepoMirrorRequest) (*models.RepoMirror, error) {
+	mirror, err := s.GetMirror(userID, mirrorID)
+	if err != nil {
+		return nil, err
+	}
+
+	password := mirror.Password
+	if err := s.fillMirror(mirror, req); err != nil {
+		return nil, err
+	}
+	if mirror.Enabled && s.isPrivate(mirror.RepoID) {
+		return nil, ErrMirrorPrivateRepo
+	}
+	if req.Password == "" {
+		mirror.Password = password
+	}
+	if err := s.validateMirror(mirror); err != nil {
+		return nil, err
+	}
+
+	if err := database.DB.Save(mirror).Error; err != nil {
+		return nil, err
+	}
+	return mirror, nil
+}
+
+// DeleteMirror 删除镜像及其副本记录，镜像中已写入的文件保留
+func (s *MirrorServiceImpl) DeleteMirror(userID int, mirrorID int) error {
+	mirror, err := s.GetMirror(userID, mirrorID)
+	if err != nil {
+		return err
+	}
+
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("mirror_id = ?", mirror.ID).Delete(&models.FileReplica{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(mirror).Error
+	})
+}
+
+// ClearRepository 删除仓库时清理以它为主仓库或镜像仓库的镜像设置
+func (s *MirrorServiceImpl) ClearRepository(userID int, repoID int) error {
+	var mirrorIDs []int
+	if err := database.DB.Model(&models.RepoMirror{}).
+		Where("user_id = ? AND (repo_id = ? OR (type = ? AND mirror_repo_id = ?))", userID, repoID, models.MirrorTypeGithub, repoID).
+		Pluck("id", &mirrorIDs).Error; err != nil {
+		return err
+	}
+	if len(mirrorIDs) == 0 {
+		return nil
+	}
+
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("mirror_id IN ?", mirrorIDs).Delete(&models.FileReplica{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("id IN ?", mirrorIDs).Delete(&models.RepoMirror{}).Error
+	})
+}
+
+// ReplicateUpload 文件上传到主仓库后，异步写入仓库的所有镜像
+func (s *MirrorServiceImpl) ReplicateUpload(file *models.File, content []byte) {
+	for _, mirror := range s.enabledMirrors(file.RepoID) {
+		replica := &models.FileReplica{
+			FileID:   file.ID,
+			MirrorID: mirror.ID,
+			UserID:   file.UserID,
+			Path:     file.URL,
+			Status:   models.ReplicaStatusPending,
+		}
+		if err := database.DB.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "file_id"}, {Name: "mirror_id"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{"path": file.URL, "status": models.ReplicaStatusPending, "attempts": 0, "error": ""}),
+		}).Create(replica).Error; err != nil {
+			logger.Errorf("create replica of file %d on mirror %d failed: %v", file.ID, mirror.ID, err)
+			continue
+		}
+
+		go s.syncReplica(mirror, *replica, content)
+	}
+}
+
+// ReplicateDelete 文件从主仓库删除后，异步从镜像中删除
+func (s *MirrorServiceImpl) ReplicateDelete(file *models.File) {
+	var replicas []models.FileReplica
+	if err := database.DB.Where("file_id = ?", file.ID).Find(&replicas).Error; err != nil {
+		logger.Errorf("get replicas of file %d failed: %v", file.ID, err)
+		return
+	}
+	if len(replicas) == 0 {
+		return
+	}
+
+	if err := database.DB.Model(&models.FileReplica{}).
+		Where("file_id = ?", file.ID).
+		Updates(map[string]interface{}{"status": models.ReplicaStatusDeleting, "attempts": 0, "error": ""}).Error; err != nil {
+		logger.Errorf("mark replicas of file %d deleting failed: %v", file.ID, err)
+		return
+	}
+
+	go func() {
+		for _, replica := range replicas {
+			mirror, err := s.GetMirror(replica.UserID, replica.MirrorID)
+			if err != nil {
+				continue
+			}
+			s.deleteReplica(*mirror, replica)
+		}
+	}()
+}
+
+// CatchUp 补偿同步：为镜像补齐缺失的副本，重试失败的写入和删除
+// repoID 为 0 时处理所有仓库
+func (s *MirrorServiceImpl) CatchUp(repoID int) error {
+	query := database.DB.Where("enabled = ?", true)
+	if repoID > 0 {
+		query = query.Where("repo_id = ?", repoID)
+	}
+
+	var mirrors []models.RepoMirror
+	if err := query.Find(&mirrors).Error; err != nil {
+		return err
+	}
+
+	for _, mirror := range mirrors {
+		if err := s.catchUpMirror(mirror); err != nil {
+			logger.Errorf("catch up mirror %d failed: %v", mirror.ID, err)
+		}
+	}
+	return nil
+}
+
+// FallbackURLs 获取文件在已同步镜像上的访问地址，调用方只传入公开仓库的文件
+func (s *MirrorServiceImpl) FallbackURLs(files []models.File) map[int][]string {
+	result := map[int][]string{}
+	if len(files) == 0 {
+		return result
+	}
+
+	fileIDs := make([]int, 0, len(files))
+	for _, file := range files {
+		fileIDs = append(fileIDs, file.ID)
+	}
+
+	var replicas []models.FileReplica
+	if err := database.DB.Where("file_id IN ? AND status = ?", fileIDs, models.ReplicaStatusSynced).Order("mirror_id ASC").Find(&replicas).Error; err != nil {
+		logger.Warnf("get replicas failed: %v", err)
+		return result
+	}
+
+	backends := map[int]mirrorBackend{}
+	for _, replica := range replicas {
+		backend, ok := backends[replica.MirrorID]
+		if !ok {
+			var mirror models.RepoMirror
+			if err := database.DB.First(&mirror, replica.MirrorID).Error; err == nil && mirror.Enabled {
+				backend, _ = s.backend(mirror)
+			}
+			backends[replica.MirrorID] = backend
+		}
+		if backend != nil {
+			result[replica.FileID] = append(result[replica.FileID], backend.URL(replica.Path))
+		}
+	}
+	return result
+}
+
+// catchUpMirror 处理单个镜像的补偿同步
+func (s *MirrorServiceImpl) catchUpMirror(mirror models.RepoMirror) error {
+	// 仓库改为私有后不再写入镜像，已有的副本全部删除
+	if s.isPrivate(mirror.RepoID) {
+		if err := database.DB.Model(&models.FileReplica{}).
+			Where("mirror_id = ? AND status <> ?", mirror.ID, models.ReplicaStatusDeleting).
+			Updates(map[string]interface{}{"status": models.ReplicaStatusDeleting, "attempts": 0, "error": ""}).Error; err != nil {
+			return err
+		}
+		return s.retryDeletes(mirror)
+	}
+
+	// 为镜像创建之前上传的文件补充副本记录
+	if err := database.DB.Exec(fmt.Sprintf(`INSERT INTO %s (file_id, mirror_id, user_id, path, status, attempts, created_at, updated_at)
+		SELECT f.id, ?, f.user_id, f.url, ?, 0, NOW(), NOW() FROM %s f
+		WHERE f.repo_id = ? AND NOT EXISTS (SELECT 1 FROM %s r WHERE r.file_id = f.id AND r.mirror_id = ?)`,
+		replicaTable(), fileTable(), replicaTable()),
+		mirror.ID, models.ReplicaStatusPending, mirror.RepoID, mirror.ID).Error; err != nil {
+		return err
+	}
+
+	// 重试写入，刚创建的 pending 副本可能仍在异步写入中，跳过
+	var replicas []models.FileReplica
+	staleBefore := time.Now().Add(-constants.ReplicaPendingMinute * time.Minute)
+	if err := database.DB.Where("mirror_id = ? AND attempts < ?", mirror.ID, constants.MaxReplicaAttempts).
+		Where("status = ? OR (status = ? AND updated_at < ?)", models.ReplicaStatusFailed, models.ReplicaStatusPending, staleBefore).
+		Order("id ASC").Limit(constants.ReplicaCatchUpBatch).
+		Find(&replicas).Error; err != nil {
+		return err
+	}
+
+	for _, replica := range replicas {
+		var file models.File
+		if err := database.DB.Unscoped().First(&file, replica.FileID).Error; err != nil {
+			// 主文件已不存在，副本记录一并清理
+			database.DB.Delete(&replica)
+			continue
+		}
+
+		content, err := FileService.ReadContent(&file)
+		if err != nil {
+			s.markFailed(replica, err)
+			continue
+		}
+		s.syncReplica(mirror, replica, content)
+	}
+
+	return s.retryDeletes(mirror)
+}
+
+// retryDeletes 重试镜像中待删除的副本
+func (s *MirrorServiceImpl) retryDeletes(mirror models.RepoMirror) error {
+	var deleting []models.FileReplica
+	if err := database.DB.Where("mirror_id = ? AND status = ? AND attempts < ?", mirror.ID, models.ReplicaStatusDeleting, constants.MaxReplicaAttempts).
+		Order("id ASC").Limit(constants.ReplicaCatchUpBatch).
+		Find(&deleting).Error; err != nil {
+		return err
+	}
+	for _, replica := range deleting {
+		s.deleteReplica(mirror, replica)
+	}
+
+	return nil
+}
+
+func (s *MirrorServiceImpl) syncReplica(mirror models.RepoMirror, replica models.FileReplica, content []byte) {
+	backend, err := s.backend(mirror)
+	if err == nil {
+		err = backend.Put(replica.Path, content)
+	}
+	if err != nil {
+		logger.Warnf("replicate file %d to mirror %d failed: %v", replica.FileID, mirror.ID, err)
+		s.markFailed(replica, err)
+		return
+	}
+
+	now := time.Now()
+	database.DB.Model(&models.FileReplica{}).Where("id = ?", replica.ID).Updates(map[string]interface{}{
+		"status":    models.ReplicaStatusSynced,
+		"attempts":  gorm.Expr("attempts + 1"),
+		"error":     "",
+		"synced_at": &now,
+	})
+}
+
+// deleteReplica 从镜像中删除副本，成功后删除副本记录
+// 移动文件时同一条记录会被改写为新路径并重新同步，只在记录仍指向被删除的路径时才修改它
+func (s *MirrorServiceImpl) deleteReplica(mirror models.RepoMirror, replica models.FileReplica) {
+	backend, err := s.backend(mirror)
+	if err == nil {
+		err = backend.Delete(replica.Path)
+	}
+
+	query := database.DB.Where("id = ? AND path = ? AND status = ?", replica.ID, replica.Path, models.ReplicaStatusDeleting)
+	if err != nil {
+		logger.Warnf("delete file %s from mirror %d failed: %v", replica.Path, mirror.ID, err)
+		query.Model(&models.FileReplica{}).Updates(map[string]interface{}{
+			"attempts": gorm.Expr("attempts + 1"),
+			"error":    replicaError(err),
+		})
+		return
+	}
+
+	query.Delete(&models.FileReplica{})
+}
+
+func (s *MirrorServiceImpl) markFailed(replica models.FileReplica, err error) {
+	database.DB.Model(&models.FileReplica{}).Where("id = ?", replica.ID).Updates(map[string]interface{}{
+		"status":   models.ReplicaStatusFailed,
+		"attempts": gorm.Expr("attempts + 1"),
+		"error":    replicaError(err),
+	})
+}
+
+// enabledMirrors 获取仓库已启用的镜像，私有仓库不写入镜像
+func (s *MirrorServiceImpl) enabledMirrors(repoID int) []models.RepoMirror {
+	if s.isPrivate(repoID) {
+		return nil
+	}
+
+	var mirrors []models.RepoMirror
+	if err := database.DB.Where("repo_id = ? AND enabled = ?", repoID, true).Find(&mirrors).Error; err != nil {
+		logger.Errorf("get mirrors of repository %d failed: %v", repoID, err)
+	}
+	return mirrors
+}
+
+// isPrivate 判断仓库是否为私有仓库，查询失败时按私有处理
+func (s *MirrorServiceImpl) isPrivate(repoID int) bool {
+	var repo models.Repository
+	if err := database.DB.Select("id", "private").First(&repo, repoID).Error; err != nil {
+		return true
+	}
+	return repo.Private
+}
+
+// backend 根据镜像类型创建存储后端
+func (s *MirrorServiceImpl) backend(mirror models.RepoMirror) (mirrorBackend, error) {
+	switch mirror.Type {
+	case models.MirrorTypeGithub:
+		var repo models.Repository
+		if err := database.DB.Where("id = ? AND user_id = ?", mirror.MirrorRepoID, mirror.UserID).First(&repo).Error; err != nil {
+			return nil, fmt.Errorf("mirror repository %d not found", mirror.MirrorRepoID)
+		}
+		return &githubMirror{repo: repo, cdnHost: ConfigService.GetFileCDNHostname(0)}, nil
+	case models.MirrorTypeWebdav:
+		password, err := utils.DecryptString(mirror.Password, mirrorSecretKey())
+		if err != nil {
+			return nil, fmt.Errorf("decrypt password of mirror %d failed: %v", mirror.ID, err)
+		}
+		mirror.Password = password
+		return &webdavMirror{mirror: mirror, client: s.client}, nil
+	}
+	return nil, fmt.Errorf("unsupported mirror type: %s", mirror.Type)
+}
+
+func (s *MirrorServiceImpl) fillMirror(mirror *models.RepoMirror, req models.RepoMirrorRequest) error {
+	// 密码加密保存，读取时在 backend 中解密
+	password, err := utils.EncryptString(req.Password, mirrorSecretKey())
+	if err != nil {
+		return fmt.Errorf("encrypt mirror password failed: %v", err)
+	}
+
+	mirror.Type = req.Type
+	mirror.MirrorRepoID = req.MirrorRepoID
+	mirror.Endpoint = strings.TrimSuffix(req.Endpoint, "/")
+	mirror.Username = req.Username
+	mirror.Password = password
+	mirror.PublicURL = strings.TrimSuffix(req.PublicURL, "/")
+	if req.Enabled != nil {
+		mirror.Enabled = *req.Enabled
+	}
+	return nil
+}
+
+func (s *MirrorServiceImpl) validateMirror(mirror *models.RepoMirror) error {
+	switch mirror.Type {
+	case models.MirrorTypeGithub:
+		if mirror.MirrorRepoID == mirror.RepoID {
+			return errors.New("mirror repository must differ from the primary repository")
+		}
+		if _, err := RepositoryService.GetRepository(mirror.UserID, mirror.MirrorRepoID); err != nil {
+			return fmt.Errorf("mirror repository %d not found", mirror.MirrorRepoID)
+		}
+		mirror.Endpoint, mirror.Username, mirror.Password, mirror.PublicURL = "", "", "", ""
+	case models.MirrorTypeWebdav:
+		if utils.IsEmpty(mirror.Endpoint) {
+			return errors.New("webdav mirror requires an endpoint")
+		}
+		// 服务端会携带凭据向该地址写入，不允许指向内网
+		if err := checkOutboundURL(mirror.Endpoint); err != nil {
+			return fmt.Errorf("invalid webdav endpoint: %v", err)
+		}
+		mirror.MirrorRepoID = 0
+	}
+	return nil
+}
+
+// mirrorSecretKey 镜像凭据的加密密钥，未单独配置 DATA_ENCRYPTION_KEY 时使用 JWT 密钥
+func mirrorSecretKey() []byte {
+	if key := viper.GetString("DATA_ENCRYPTION_KEY"); key != "" {
+		return []byte(key)
+	}
+	return []byte(config.Config.Server.Secret)
+}
+
+// replicaError 截断错误信息，避免超出字段长度
+func replicaError(err error) string {
+	msg := err.Error()
+	if len(msg) > 500 {
+		msg = msg[:500]
+	}
+	return msg
+}
+
+func replicaTable() string {
+	return database.DB.NamingStrategy.TableName("FileReplica")
+}
+
+func fileTable() string {
+	return database.DB.NamingStrategy.TableName("File")
+}
+
+// githubMirror 以用户的另一个 GitHub 仓库作为镜像
+type githubMirror struct {
+	repo    models.Repository
+	cdnHost string
+}
+
+// Put 写入镜像仓库，文件已存在时 GitHub 返回 422
+// 已有内容与本次一致时说明之前已写入成功，否则按当前 SHA 覆盖，例如主仓库文件被替换后
+func (m *githubMirror) Put(remotePath string, content []byte) error {
+	err := GithubService.UploadFile(m.repo.UserID, m.repo.RepoURL, remotePath, bytes.NewReader(content))
+	if err == nil || !strings.Contains(err.Error(), "422") {
+		return err
+	}
+
+	expected, err := utils.CalculateGitHash(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return err
+	}
+	sha, err := GithubService.GetFileSHA(m.repo.UserID, m.repo.RepoURL, remotePath)
+	if err != nil {
+		return err
+	}
+	if sha == expected {
+		return nil
+	}
+
+	message := fmt.Sprintf("Update mirror file: %s", path.Base(remotePath))
+	_, err = GithubService.UpdateFile(m.repo.UserID, m.repo.RepoURL, remotePath, content, message, sha)
+	return err
+}
+
+func (m *githubMirror) Delete(remotePath string) error {
+	err := GithubService.DeleteFile(m.repo.UserID, m.repo.RepoURL, remotePath)
+	if err != nil && strings.Contains(err.Error(), "404") {
+		return nil
+	}
+	return err
+}
+
+func (m *githubMirror) URL(remotePath string) string {
+	url, _ := m.repo.FileURLs(&models.File{URL: remotePath}, m.cdnHost)
+	return url
+}
+
+// webdavMirror 通过 WebDAV 的 PUT/DELETE 写入镜像
+type webdavMirror struct {
+	mirror models.RepoMirror
+	client *http.Client
+}
+
+func (m *webdavMirror) Put(remotePath string, content []byte) error {
+	// 逐级创建父目录，目录已存在时服务端返回 405
+	dir := path.Dir(remotePath)
+	if dir != "." && dir != "/" {
+		current := ""
+		for _, part := range strings.Split(strings.Trim(dir, "/"), "/") {
+			current += "/" + part
+			resp, err := m.do("MKCOL", current, nil)
+			if err != nil {
+				return err
+			}
+			if resp.StatusCode >= 300 && resp.StatusCode != http.StatusMethodNotAllowed {
+				return fmt.Errorf("webdav mkcol %s failed: %s", current, resp.Status)
+			}
+		}
+	}
+
+	resp, err := m.do(http.MethodPut, "/"+strings.TrimPrefix(remotePath, "/"), content)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webdav put %s failed: %s", remotePath, resp.Status)
+	}
+	return nil
+}
+
+func (m *webdavMirror) Delete(remotePath string) error {
+	resp, err := m.do(http.MethodDelete, "/"+strings.TrimPrefix(remotePath, "/"), nil)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 && resp.StatusCode != http.StatusNotFound {
+		return fmt.Errorf("webdav delete %s failed: %s", remotePath, resp.Status)
+	}
+	return nil
+}
+
+func (m *webdavMirror) URL(remotePath string) string {
+	base := utils.If(m.mirror.PublicURL == "", m.mirror.Endpoint, m.mirror.PublicURL)
+	return base + "/" + strings.TrimPrefix(remotePath, "/")
+}
+
+func (m *webdavMirror) do(method string, remotePath string, body []byte) (*http.Response, error) {
+	req, err := http.NewRequest(method, m.mirror.Endpoint+remotePath, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if m.mirror.Username != "" {
+		req.SetBasicAuth(m.mirror.Username, m.mirror.Password)
+	}
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+	return resp, nil
+}
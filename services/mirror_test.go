@@ -0,0 +1,34 @@
+package services
+
+import (
+	"bytes"
+	"testing"
+)
+
+// 镜像中已有同一路径的文件时，内容不同则覆盖，内容相同则直接视为成功
+func TestGithubMirrorPutExisting(t *testing.T) {
+	gh := newFakeGithub(t)
+	mirror := &githubMirror{repo: *testRepository()}
+
+	gh.files["images/a.png"] = []byte("old content")
+	if err := mirror.Put("images/a.png", []byte("new content")); err != nil {
+		t.Fatalf("put stale file: %v", err)
+	}
+	if !bytes.Equal(gh.files["images/a.png"], []byte("new content")) {
+		t.Fatalf("mirror file not updated: %q", gh.files["images/a.png"])
+	}
+
+	if err := mirror.Put("images/a.png", []byte("new content")); err != nil {
+		t.Fatalf("put identical file: %v", err)
+	}
+}
+
+func TestGithubMirrorPutError(t *testing.T) {
+	gh := newFakeGithub(t)
+	gh.contentsStatus = 500
+	mirror := &githubMirror{repo: *testRepository()}
+
+	if err := mirror.Put("images/a.png", []byte("content")); err == nil {
+		t.Fatal("expected error")
+	}
+}
@@ -0,0 +1,30 @@
+package services
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+	"pichub.api/pkg/utils"
+)
+
+// outboundAllowedHosts 允许访问的内网主机，逗号分隔，用于自建的 WebDAV、CDN 刷新等服务
+// 用户可以填写地址的功能默认只能访问公网地址
+func outboundAllowedHosts() []string {
+	value := viper.GetString("OUTBOUND_ALLOWED_HOSTS")
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
+// checkOutboundURL 检查用户填写的地址是否可以由服务端访问
+func checkOutboundURL(rawURL string) error {
+	return utils.CheckPublicURL(rawURL, outboundAllowedHosts())
+}
+
+// newOutboundClient 创建访问用户填写的地址使用的 HTTP 客户端，连接时拒绝内网地址
+func newOutboundClient(timeout time.Duration) *http.Client {
+	return utils.NewPublicHTTPClient(timeout, outboundAllowedHosts)
+}
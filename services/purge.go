@@ -0,0 +1,293 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+	"pichub.api/infra/database"
+	"pichub.api/infra/logger"
+	"pichub.api/models"
+	"pichub.api/pkg/utils"
+)
+
+type PurgeServiceImpl struct {
+	client *http.Client
+}
+
+var PurgeService = &PurgeServiceImpl{
+	client: &http.Client{Timeout: 30 * time.Second},
+}
+
+// 刷新触发原因
+const (
+	PurgeReasonDelete  = "delete"
+	PurgeReasonReplace = "replace"
+	PurgeReasonManual  = "manual"
+)
+
+const (
+	defaultJsdelivrPurgeEndpoint = "https://purge.jsdelivr.net"
+	defaultCloudflareAPIEndpoint = "https://api.cloudflare.com/client/v4"
+	jsdelivrCDNHost              = "cdn.jsdelivr.net"
+	cloudflarePurgeBatch         = 30  // Cloudflare 单次请求最多刷新的地址数
+	purgeErrorLimit              = 500 // 日志中错误信息的最大长度
+)
+
+// purgeResult 单个地址的刷新结果
+type purgeResult struct {
+	provider   string
+	url        string
+	statusCode int
+	err        error
+}
+
+// GetConfig 读取用户的刷新配置，用户未配置时使用系统配置
+// 刷新接口地址和通用刷新模板会由服务端直接请求，只读取系统配置，用户只能开关 jsDelivr 和填写自己的 Cloudflare 区域
+// 系统配置对所有用户可见，系统的 Cloudflare Token 和通用刷新请求头从环境变量读取，不保存在 config 表中
+func (s *PurgeServiceImpl) GetConfig(userID int) (*models.PurgeConfig, error) {
+	system, err := ConfigService.GetByType("purge", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	values := system
+	token := viper.GetString("PURGE_CLOUDFLARE_TOKEN")
+	if userID != 0 {
+		user, err := ConfigService.GetByType("purge", userID)
+		if err != nil {
+			return nil, err
+		}
+		if len(user) > 0 {
+			values = user
+			token = utils.ToString(user["cloudflare_token"])
+		}
+	}
+
+	config := &models.PurgeConfig{
+		Jsdelivr:           utils.ToBool(values["jsdelivr"], false),
+		JsdelivrEndpoint:   utils.ToString(system["jsdelivr_endpoint"]),
+		CloudflareZoneID:   utils.ToString(values["cloudflare_zone_id"]),
+		CloudflareToken:    token,
+		CloudflareEndpoint: utils.ToString(system["cloudflare_endpoint"]),
+		HTTPTemplate:       utils.ToString(system["http_template"]),
+		HTTPMethod:         strings.ToUpper(utils.ToString(system["http_method"])),
+		HTTPHeaders:        viper.GetString("PURGE_HTTP_HEADERS"),
+	}
+
+	if config.JsdelivrEndpoint == "" {
+		config.JsdelivrEndpoint = defaultJsdelivrPurgeEndpoint
+	}
+	if config.CloudflareEndpoint == "" {
+		config.CloudflareEndpoint = defaultCloudflareAPIEndpoint
+	}
+	if config.HTTPMethod == "" {
+		config.HTTPMethod = http.MethodPost
+	}
+
+	return config, nil
+}
+
+// PurgeFileAsync 在后台刷新文件的 CDN 缓存，用于删除和覆盖文件后
+func (s *PurgeServiceImpl) PurgeFileAsync(file models.File, reason string) {
+	go func() {
+		if _, err := s.PurgeFile(&file, reason); err != nil {
+			logger.Warnf("purge cdn cache of file %d failed: %v", file.ID, err)
+		}
+	}()
+}
+
+// PurgeFile 按用户配置刷新文件所有访问地址的 CDN 缓存，返回本次写入的刷新日志
+func (s *PurgeServiceImpl) PurgeFile(file *models.File, reason string) ([]models.PurgeLog, error) {
+	config, err := s.GetConfig(file.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	urls, repo := FileService.FileURLs(file)
+
+	var results []purgeResult
+	if config.Jsdelivr {
+		results = append(results, s.purgeJsdelivr(config, s.jsdelivrURLs(urls, repo, file))...)
+	}
+	if config.CloudflareZoneID != "" {
+		results = append(results, s.purgeCloudflare(config, urls)...)
+	}
+	if config.HTTPTemplate != "" {
+		results = append(results, s.purgeHTTP(config, urls, file)...)
+	}
+
+	logs := []models.PurgeLog{}
+	for _, result := range results {
+		log := models.PurgeLog{
+			UserID:     file.UserID,
+			FileID:     file.ID,
+			Provider:   result.provider,
+			URL:        result.url,
+			Reason:     reason,
+			Status:     models.PurgeStatusSuccess,
+			StatusCode: result.statusCode,
+		}
+		if result.err != nil {
+			log.Status = models.PurgeStatusFailed
+			log.Error = result.err.Error()
+			if len(log.Error) > purgeErrorLimit {
+				log.Error = log.Error[:purgeErrorLimit]
+			}
+		}
+		logs = append(logs, log)
+	}
+
+	if len(logs) > 0 {
+		if err := database.DB.Create(&logs).Error; err != nil {
+			return logs, err
+		}
+	}
+	return logs, nil
+}
+
+// ListLogs 分页获取用户的刷新日志，fileID 不为 0 时只返回该文件的日志
+func (s *PurgeServiceImpl) ListLogs(userID int, fileID int, page int, pageSize int) ([]models.PurgeLog, int64, error) {
+	var total int64
+	var logs []models.PurgeLog
+
+	query := database.DB.Model(&models.PurgeLog{}).Where("user_id = ?", userID)
+	if fileID > 0 {
+		query = query.Where("file_id = ?", fileID)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	if err := query.Order("id DESC").Offset(offset).Limit(pageSize).Find(&logs).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return logs, total, nil
+}
+
+// jsdelivrURLs 收集需要刷新的 jsDelivr 地址，仓库文件总是包含 jsdelivr 预设生成的地址
+func (s *PurgeServiceImpl) jsdelivrURLs(urls []string, repo *models.Repository, file *models.File) []string {
+	seen := map[string]bool{}
+	var result []string
+	add := func(u string) {
+		if !seen[u] {
+			seen[u] = true
+			result = append(result, u)
+		}
+	}
+
+	if repo != nil && file.RemoteURL == "" {
+		add(repo.RenderURL(models.URLPresetJsdelivr, file, ""))
+	}
+	for _, u := range urls {
+		if parsed, err := url.Parse(u); err == nil && parsed.Host == jsdelivrCDNHost {
+			add(u)
+		}
+	}
+	return result
+}
+
+// purgeJsdelivr 调用 jsDelivr 刷新接口，将 cdn.jsdelivr.net 替换为刷新地址
+func (s *PurgeServiceImpl) purgeJsdelivr(config *models.PurgeConfig, urls []string) []purgeResult {
+	var results []purgeResult
+	for _, u := range urls {
+		parsed, err := url.Parse(u)
+		if err != nil {
+			results = append(results, purgeResult{provider: models.PurgeProviderJsdelivr, url: u, err: err})
+			continue
+		}
+
+		endpoint := strings.TrimSuffix(config.JsdelivrEndpoint, "/") + parsed.EscapedPath()
+		code, err := s.send(http.MethodGet, endpoint, nil, nil)
+		results = append(results, purgeResult{provider: models.PurgeProviderJsdelivr, url: u, statusCode: code, err: err})
+	}
+	return results
+}
+
+// purgeCloudflare 调用 Cloudflare 区域的按地址刷新接口
+func (s *PurgeServiceImpl) purgeCloudflare(config *models.PurgeConfig, urls []string) []purgeResult {
+	var results []purgeResult
+	endpoint := fmt.Sprintf("%s/zones/%s/purge_cache", strings.TrimSuffix(config.CloudflareEndpoint, "/"), url.PathEscape(config.CloudflareZoneID))
+	headers := map[string]string{
+		"Authorization": "Bearer " + config.CloudflareToken,
+		"Content-Type":  "application/json",
+	}
+
+	// 单次请求的地址数量有限制，分批提交
+	for start := 0; start < len(urls); start += cloudflarePurgeBatch {
+		end := start + cloudflarePurgeBatch
+		if end > len(urls) {
+			end = len(urls)
+		}
+		batch := urls[start:end]
+
+		body, _ := json.Marshal(map[string][]string{"files": batch})
+		code, err := s.send(http.MethodPost, endpoint, body, headers)
+		for _, u := range batch {
+			results = append(results, purgeResult{provider: models.PurgeProviderCloudflare, url: u, statusCode: code, err: err})
+		}
+	}
+	return results
+}
+
+// purgeHTTP 按通用模板逐个刷新地址
+func (s *PurgeServiceImpl) purgeHTTP(config *models.PurgeConfig, urls []string, file *models.File) []purgeResult {
+	headers := map[string]string{}
+	for _, line := range strings.Split(config.HTTPHeaders, "\n") {
+		if key, value, ok := strings.Cut(line, ":"); ok {
+			headers[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+
+	var results []purgeResult
+	for _, u := range urls {
+		endpoint := strings.NewReplacer(
+			"{url}", url.QueryEscape(u),
+			"{path}", escapePath(file.URL),
+		).Replace(config.HTTPTemplate)
+
+		code, err := s.send(config.HTTPMethod, endpoint, nil, headers)
+		results = append(results, purgeResult{provider: models.PurgeProviderHTTP, url: u, statusCode: code, err: err})
+	}
+	return results
+}
+
+// escapePath 逐段转义仓库路径，保留分隔符
+func escapePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+// send 发送刷新请求，非 2xx 响应视为失败
+// 响应内容不写入日志，日志对用户可见，只记录状态码
+func (s *PurgeServiceImpl) send(method string, endpoint string, body []byte, headers map[string]string) (int, error) {
+	req, err := http.NewRequest(method, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
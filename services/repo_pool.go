@@ -0,0 +1,332 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm"
+	"pichub.api/constants"
+	"pichub.api/infra/database"
+	"pichub.api/infra/logger"
+	"pichub.api/models"
+	"pichub.api/pkg/utils"
+)
+
+type RepoPoolServiceImpl struct {
+	// 防止并发上传同时触发切换或重复创建仓库
+	mu sync.Mutex
+}
+
+var RepoPoolService = &RepoPoolServiceImpl{}
+
+// ListPools 获取用户的所有仓库池
+func (s *RepoPoolServiceImpl) ListPools(userID int) ([]models.RepoPool, error) {
+	var pools []models.RepoPool
+	if err := database.DB.Where("user_id = ?", userID).Order("id ASC").Find(&pools).Error; err != nil {
+		return nil, err
+	}
+	return pools, nil
+}
+
+// GetPool 获取仓库池
+func (s *RepoPoolServiceImpl) GetPool(userID int, poolID int) (*models.RepoPool, error) {
+	var pool models.RepoPool
+	if err := database.DB.Where("id = ? AND user_id = ?", poolID, userID).First(&pool).Error; err != nil {
+		return nil, err
+	}
+	return &pool, nil
+}
+
+// GetPoolDetail 获取仓库池及成员仓库的当前容量
+func (s *RepoPoolServiceImpl) GetPoolDetail(userID int, poolID int) (*models.RepoPoolResponse, error) {
+	pool, err := s.GetPool(userID, poolID)
+	if err != nil {
+		return nil, err
+	}
+
+	members, err := s.getMembers(pool)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.RepoPoolResponse{RepoPool: *pool, Members: []models.RepoPoolMember{}}
+	for _, repo := range members {
+		size, err := s.repositorySize(pool, &repo)
+		if err != nil {
+			logger.Warnf("get size of repository %d failed: %v", repo.ID, err)
+		}
+		response.Members = append(response.Members, models.RepoPoolMember{
+			RepositoryResponse: repo.ToResponse(),
+			Size:               size,
+			Active:             repo.ID == pool.ActiveRepoID,
+		})
+	}
+
+	return response, nil
+}
+
+// CreatePool 创建仓库池，第一个成员仓库作为当前活动仓库
+func (s *RepoPoolServiceImpl) CreatePool(userID int, req models.RepoPoolRequest) (*models.RepoPool, error) {
+	pool := &models.RepoPool{UserID: userID}
+	s.fillPool(pool, req)
+
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(pool).Error; err != nil {
+			return err
+		}
+		for _, repoID := range req.RepoIDs {
+			if err := s.addMember(tx, pool, repoID); err != nil {
+				return err
+			}
+		}
+		if len(req.RepoIDs) > 0 {
+			pool.ActiveRepoID = req.RepoIDs[0]
+			return tx.Model(pool).Update("active_repo_id", pool.ActiveRepoID).Error
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return pool, nil
+}
+
+// UpdatePool 更新仓库池设置，成员通过 AddMember/RemoveMember 管理
+func (s *RepoPoolServiceImpl) UpdatePool(userID int, poolID int, req models.RepoPoolRequest) (*models.RepoPool, error) {
+	pool, err := s.GetPool(userID, poolID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.fillPool(pool, req)
+	if err := database.DB.Save(pool).Error; err != nil {
+		return nil, err
+	}
+	return pool, nil
+}
+
+// DeletePool 删除仓库池，成员仓库保留
+func (s *RepoPoolServiceImpl) DeletePool(userID int, poolID int) error {
+	pool, err := s.GetPool(userID, poolID)
+	if err != nil {
+		return err
+	}
+
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Repository{}).Where("pool_id = ? AND user_id = ?", pool.ID, userID).Update("pool_id", 0).Error; err != nil {
+			return err
+		}
+		return tx.Delete(pool).Error
+	})
+}
+
+// AddMember 将仓库加入仓库池
+func (s *RepoPoolServiceImpl) AddMember(userID int, poolID int, repoID int) error {
+	pool, err := s.GetPool(userID, poolID)
+	if err != nil {
+		return err
+	}
+
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := s.addMember(tx, pool, repoID); err != nil {
+			return err
+		}
+		if pool.ActiveRepoID == 0 {
+			return tx.Model(pool).Update("active_repo_id", repoID).Error
+		}
+		return nil
+	})
+}
+
+// RemoveMember 将仓库移出仓库池，移除活动仓库时切换到下一个成员
+func (s *RepoPoolServiceImpl) RemoveMember(userID int, poolID int, repoID int) error {
+	pool, err := s.GetPool(userID, poolID)
+	if err != nil {
+		return err
+	}
+
+	result := database.DB.Model(&models.Repository{}).
+		Where("id = ? AND user_id = ? AND pool_id = ?", repoID, userID, pool.ID).
+		Update("pool_id", 0)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("repository is not a member of this pool")
+	}
+
+	if pool.ActiveRepoID == repoID {
+		var next models.Repository
+		nextID := 0
+		if err := database.DB.Where("pool_id = ? AND user_id = ?", pool.ID, userID).Order("id ASC").First(&next).Error; err == nil {
+			nextID = next.ID
+		}
+		return database.DB.Model(pool).Update("active_repo_id", nextID).Error
+	}
+
+	return nil
+}
+
+// ResolveRepository 为即将上传的文件选择仓库池中的仓库
+// 活动仓库加上本次文件超过阈值时，依次切换到下一个未满的成员，都满时按配置自动创建新仓库
+// 选择仓库之前先完成系统和用户级的上传策略和配额检查，避免为会被拒绝的文件查询仓库大小或创建仓库
+func (s *RepoPoolServiceImpl) ResolveRepository(userID int, poolID int, incomingSize int64) (int, error) {
+	if err := UploadPolicyService.CheckSize(userID, 0, incomingSize); err != nil {
+		return 0, err
+	}
+	if err := StorageService.CheckQuota(userID, 0, incomingSize); err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pool, err := s.GetPool(userID, poolID)
+	if err != nil {
+		return 0, errors.New("repository pool not found")
+	}
+
+	// 单个文件超过阈值时任何仓库都放不下，新建仓库也无济于事
+	if incomingSize > pool.Threshold {
+		return 0, &UploadRejectedError{
+			Code: constants.ErrCodeFileTooLarge,
+			Reason: fmt.Sprintf("file size %s exceeds the threshold %s of pool %s",
+				utils.FriendlyFileSize(incomingSize), utils.FriendlyFileSize(pool.Threshold), pool.Name),
+		}
+	}
+
+	members, err := s.getMembers(pool)
+	if err != nil {
+		return 0, err
+	}
+
+	// 优先使用当前活动仓库
+	for _, repo := range members {
+		if repo.ID == pool.ActiveRepoID {
+			if s.hasRoom(pool, &repo, incomingSize) {
+				return repo.ID, nil
+			}
+			break
+		}
+	}
+
+	// 活动仓库已满，按顺序寻找下一个可用仓库
+	for _, repo := range members {
+		if repo.ID == pool.ActiveRepoID {
+			continue
+		}
+		if s.hasRoom(pool, &repo, incomingSize) {
+			return repo.ID, s.switchActive(pool, repo.ID)
+		}
+	}
+
+	if !pool.AutoCreate {
+		return 0, &UploadRejectedError{
+			Code:   constants.ErrCodePoolFull,
+			Reason: fmt.Sprintf("all repositories in pool %s have reached the size threshold", pool.Name),
+		}
+	}
+
+	repo, err := s.createMember(pool, len(members)+1)
+	if err != nil {
+		return 0, err
+	}
+	return repo.ID, s.switchActive(pool, repo.ID)
+}
+
+// hasRoom 判断仓库在写入本次文件后是否仍低于阈值，且满足仓库级的上传策略和配额
+func (s *RepoPoolServiceImpl) hasRoom(pool *models.RepoPool, repo *models.Repository, incomingSize int64) bool {
+	if err := UploadPolicyService.CheckSize(repo.UserID, repo.ID, incomingSize); err != nil {
+		return false
+	}
+	if err := StorageService.CheckQuota(repo.UserID, repo.ID, incomingSize); err != nil {
+		return false
+	}
+
+	size, err := s.repositorySize(pool, repo)
+	if err != nil {
+		logger.Warnf("get size of repository %d failed, skip it: %v", repo.ID, err)
+		return false
+	}
+	return size+incomingSize <= pool.Threshold
+}
+
+// repositorySize 按仓库池的统计方式获取仓库大小
+func (s *RepoPoolServiceImpl) repositorySize(pool *models.RepoPool, repo *models.Repository) (int64, error) {
+	if pool.Measure == models.PoolMeasureGithub {
+		return GithubService.GetRepositorySize(repo.UserID, repo.RepoURL)
+	}
+
+	stat, err := StorageService.GetRepositoryUsage(repo.UserID, repo.ID)
+	if err != nil {
+		return 0, err
+	}
+	return stat.TotalSize, nil
+}
+
+func (s *RepoPoolServiceImpl) switchActive(pool *models.RepoPool, repoID int) error {
+	logger.Infof("repository pool %d switches active repository from %d to %d", pool.ID, pool.ActiveRepoID, repoID)
+	pool.ActiveRepoID = repoID
+	return database.DB.Model(pool).Update("active_repo_id", repoID).Error
+}
+
+// createMember 在GitHub上创建新仓库并加入仓库池
+func (s *RepoPoolServiceImpl) createMember(pool *models.RepoPool, seq int) (*models.Repository, error) {
+	template := utils.If(pool.NameTemplate == "", pool.Name+"-{n}", pool.NameTemplate)
+
+	// 仓库名可能已被占用，尝试几个后续序号
+	var lastErr error
+	for i := 0; i < 5; i++ {
+		name := strings.ReplaceAll(template, "{n}", strconv.Itoa(seq+i))
+
+		repo, err := RepositoryService.CreateRepository(pool.UserID, models.CreateRepositoryRequest{
+			RepoName: name,
+			Private:  pool.Private,
+		})
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		repo.PoolID = pool.ID
+		if err := database.DB.Model(repo).Update("pool_id", pool.ID).Error; err != nil {
+			return nil, err
+		}
+		return repo, nil
+	}
+
+	return nil, fmt.Errorf("failed to create repository for pool %s: %v", pool.Name, lastErr)
+}
+
+// addMember 校验仓库归属后加入仓库池
+func (s *RepoPoolServiceImpl) addMember(tx *gorm.DB, pool *models.RepoPool, repoID int) error {
+	var repo models.Repository
+	if err := tx.Where("id = ? AND user_id = ?", repoID, pool.UserID).First(&repo).Error; err != nil {
+		return fmt.Errorf("repository %d not found", repoID)
+	}
+	if repo.PoolID != 0 && repo.PoolID != pool.ID {
+		return fmt.Errorf("repository %d already belongs to another pool", repoID)
+	}
+	return tx.Model(&repo).Update("pool_id", pool.ID).Error
+}
+
+func (s *RepoPoolServiceImpl) getMembers(pool *models.RepoPool) ([]models.Repository, error) {
+	var members []models.Repository
+	if err := database.DB.Where("pool_id = ? AND user_id = ?", pool.ID, pool.UserID).Order("id ASC").Find(&members).Error; err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+func (s *RepoPoolServiceImpl) fillPool(pool *models.RepoPool, req models.RepoPoolRequest) {
+	pool.Name = req.Name
+	pool.Threshold = req.Threshold
+	pool.Measure = utils.If(req.Measure == "", models.PoolMeasureStored, req.Measure)
+	pool.AutoCreate = req.AutoCreate
+	pool.NameTemplate = req.NameTemplate
+	pool.Private = req.Private
+}
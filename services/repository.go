@@ -2,9 +2,13 @@ package services
 
 import (
 	"errors"
+	"fmt"
+	"strings"
 
+	"github.com/google/go-github/v65/github"
 	"pichub.api/constants"
 	"pichub.api/infra/database"
+	"pichub.api/infra/logger"
 	"pichub.api/models"
 	"pichub.api/pkg/utils"
 )
@@ -13,6 +17,9 @@ type repositoryService struct{}
 
 var RepositoryService = new(repositoryService)
 
+// ErrRepositoryNotEmpty 仓库中还有文件
+var ErrRepositoryNotEmpty = errors.New("repository still has files, delete or move them and empty the trash first")
+
 func (s *repositoryService) AddRepository(userID int, repoName string, repoURL string, repoBranch string) (*models.Repository, error) {
 
 	// 检测记录是否已存在
@@ -34,12 +41,18 @@ func (s *repositoryService) AddRepository(userID int, repoName string, repoURL s
 		return nil, err
 	}
 
+	private, err := GithubService.IsRepositoryPrivate(repoURL, token)
+	if err != nil {
+		return nil, err
+	}
+
 	// 创建仓库记录
 	repository = &models.Repository{
 		UserID:     userID,
 		RepoName:   repoName,
 		RepoURL:    repoURL,
 		RepoBranch: repoBranch,
+		Private:    private,
 	}
 
 	if err := database.DB.Create(repository).Error; err != nil {
@@ -49,6 +62,82 @@ func (s *repositoryService) AddRepository(userID int, repoName string, repoURL s
 	return repository, nil
 }
 
+// 新建仓库时写入的初始文件
+const (
+	repositoryReadme = "# %s\n\n%s\n\nThis repository is managed by PicHub.\n"
+	// 图片等二进制文件不做换行符转换和 diff
+	repositoryGitattributes = `*.png binary
+*.jpg binary
+*.jpeg binary
+*.gif binary
+*.webp binary
+*.bmp binary
+*.ico binary
+*.avif binary
+*.mp4 binary
+*.mp3 binary
+lfs/** filter=lfs diff=lfs merge=lfs -text
+`
+)
+
+// CreateRepository 使用用户的 token 在GitHub上创建仓库，写入初始文件并登记仓库记录
+// 初始化失败时删除刚创建的仓库，不留下没有记录的空仓库
+func (s *repositoryService) CreateRepository(userID int, req models.CreateRepositoryRequest) (*models.Repository, error) {
+	token, err := ConfigService.GetGithubToken(userID)
+	if err != nil || utils.IsEmpty(token) {
+		return nil, errors.New("请先配置 github token")
+	}
+
+	description := utils.If(req.Description == "", "PicHub storage repository", req.Description)
+	created, err := GithubService.CreateRepository(token, req.Org, req.RepoName, description, req.Private)
+	if err != nil {
+		return nil, err
+	}
+
+	repository, err := s.seedRepository(userID, req, created, description)
+	if err != nil {
+		return nil, s.removeCreated(token, created, err)
+	}
+
+	return repository, nil
+}
+
+// seedRepository 写入初始文件并创建仓库记录
+func (s *repositoryService) seedRepository(userID int, req models.CreateRepositoryRequest, created *github.Repository, description string) (*models.Repository, error) {
+	repoURL := created.GetHTMLURL()
+
+	// 写入 README 和 .gitattributes，第一次提交会生成默认分支
+	readme := fmt.Sprintf(repositoryReadme, req.RepoName, description)
+	if err := GithubService.CommitFile(userID, repoURL, "README.md", []byte(readme), "Initialize repository"); err != nil {
+		return nil, err
+	}
+	if err := GithubService.CommitFile(userID, repoURL, ".gitattributes", []byte(repositoryGitattributes), "Add .gitattributes"); err != nil {
+		return nil, err
+	}
+
+	repository := &models.Repository{
+		UserID:     userID,
+		RepoName:   req.RepoName,
+		RepoURL:    repoURL,
+		RepoBranch: utils.If(created.GetDefaultBranch() == "", constants.DefaultRepoBranch, created.GetDefaultBranch()),
+		Private:    created.GetPrivate(),
+	}
+	if err := database.DB.Create(repository).Error; err != nil {
+		return nil, err
+	}
+	return repository, nil
+}
+
+// removeCreated 删除初始化失败的仓库，删除也失败时在错误中带上仓库地址，由用户手动处理
+func (s *repositoryService) removeCreated(token string, created *github.Repository, cause error) error {
+	err := GithubService.DeleteRepository(token, created.GetOwner().GetLogin(), created.GetName())
+	if err == nil {
+		return cause
+	}
+	logger.Warnf("remove repository %s after failed setup: %v", created.GetHTMLURL(), err)
+	return fmt.Errorf("%v; the repository %s was created on GitHub but could not be removed, please delete it manually", cause, created.GetHTMLURL())
+}
+
 func (s *repositoryService) InitRepository(userID int, repoID int) (*models.Repository, error) {
 	// 获取仓库信息
 	repository, err := s.GetRepository(userID, repoID)
@@ -61,6 +150,11 @@ func (s *repositoryService) InitRepository(userID int, repoID int) (*models.Repo
 		return nil, err
 	}
 
+	// 初始化会批量写入文件记录，重新统计用量
+	if err := StorageService.Recalculate(userID); err != nil {
+		return nil, err
+	}
+
 	return repository, nil
 }
 
@@ -93,11 +187,17 @@ func (s *repositoryService) UpdateRepository(userID int, repoID int, repoName st
 		return err
 	}
 
+	private, err := GithubService.IsRepositoryPrivate(repoURL, token)
+	if err != nil {
+		return err
+	}
+
 	// 更新仓库信息
 	updates := map[string]interface{}{
 		"repo_name":   repoName,
 		"repo_url":    repoURL,
 		"repo_branch": repoBranch,
+		"private":     private,
 	}
 
 	result := database.DB.Model(&models.Repository{}).
@@ -115,9 +215,97 @@ func (s *repositoryService) UpdateRepository(userID int, repoID int, repoName st
 	return nil
 }
 
+// checkURLTemplateHost 检查模板生成的地址是否指向公网，链接检查会请求这些地址
+// {cdn_host} 开头的模板使用系统配置的 CDN 地址，不做检查
+func (s *repositoryService) checkURLTemplateHost(repository *models.Repository, template string, customDomain string) error {
+	if preset, ok := models.URLPresets[template]; ok {
+		template = preset
+	}
+	if strings.HasPrefix(template, "{cdn_host}") {
+		return nil
+	}
+
+	sample := *repository
+	sample.CustomDomain = strings.TrimSuffix(customDomain, "/")
+	rendered := sample.RenderURL(template, &models.File{URL: "sample.png", HashValue: "sample"}, "")
+	if err := checkOutboundURL(rendered); err != nil {
+		return fmt.Errorf("url template %q: %v", template, err)
+	}
+	return nil
+}
+
+// SetURLTemplates 设置仓库的访问地址模板
+func (s *repositoryService) SetURLTemplates(userID int, repoID int, req models.RepositoryURLTemplateRequest) (*models.Repository, error) {
+	repository, err := s.GetRepository(userID, repoID)
+	if err != nil {
+		return nil, err
+	}
+
+	templates := append([]string{req.URLTemplate}, req.AltURLTemplates...)
+	for i, template := range templates {
+		template = strings.TrimSpace(template)
+		if i == 0 && template == "" {
+			continue
+		}
+		if strings.Contains(template, ",") {
+			return nil, fmt.Errorf("url template %q must not contain commas", template)
+		}
+		if err := models.ValidateURLTemplate(template); err != nil {
+			return nil, err
+		}
+		if (template == models.URLPresetCustom || strings.Contains(template, "{domain}")) && req.CustomDomain == "" {
+			return nil, errors.New("custom domain is required for the custom preset")
+		}
+		if err := s.checkURLTemplateHost(repository, template, req.CustomDomain); err != nil {
+			return nil, err
+		}
+	}
+
+	repository.URLTemplate = strings.TrimSpace(req.URLTemplate)
+	repository.AltURLTemplates = strings.Join(req.AltURLTemplates, ",")
+	repository.CustomDomain = strings.TrimSuffix(req.CustomDomain, "/")
+
+	if err := database.DB.Model(repository).Updates(map[string]interface{}{
+		"url_template":      repository.URLTemplate,
+		"alt_url_templates": repository.AltURLTemplates,
+		"custom_domain":     repository.CustomDomain,
+	}).Error; err != nil {
+		return nil, err
+	}
+	return repository, nil
+}
+
+// DeleteRepository 删除仓库记录，不会删除 GitHub 上的仓库
+// 仓库中还有文件（包括回收站中的文件）时不允许删除，避免绕过回收站直接丢失文件记录
+// 需要先删除或移动文件，并从回收站中彻底删除
 func (s *repositoryService) DeleteRepository(userID int, repoID int) error {
-	// 先删除文件
-	if err := database.DB.Where("repo_id = ? and user_id = ?", repoID, userID).Delete(&models.File{}).Error; err != nil {
+	var count int64
+	if err := database.DB.Unscoped().Model(&models.File{}).Where("repo_id = ? AND user_id = ?", repoID, userID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrRepositoryNotEmpty
+	}
+
+	// 仓库属于仓库池时先移出，必要时切换活动仓库
+	if repository, err := s.GetRepository(userID, repoID); err == nil && repository.PoolID != 0 {
+		if err := RepoPoolService.RemoveMember(userID, repository.PoolID, repoID); err != nil {
+			return err
+		}
+	}
+
+	// 已移动到其他仓库的文件在本仓库中的历史版本随仓库一起删除，无法再回滚到这些版本
+	if err := database.DB.Where("repo_id = ?", repoID).Delete(&models.FileVersion{}).Error; err != nil {
+		return err
+	}
+
+	// 清理仓库用量统计
+	if err := StorageService.ClearRepository(userID, repoID); err != nil {
+		return err
+	}
+
+	// 清理镜像设置
+	if err := MirrorService.ClearRepository(userID, repoID); err != nil {
 		return err
 	}
 
@@ -1,57 +1,101 @@
 package services
 
 import (
-	"log"
 	"time"
 
 	"github.com/robfig/cron/v3"
 	"github.com/spf13/viper"
+	"pichub.api/infra/logger"
 )
 
 type SchedulerServiceImpl struct {
 	cron *cron.Cron
 }
 
-var SchedulerService = &SchedulerServiceImpl{
-	cron: cron.New(cron.WithLocation(time.Local)),
-}
+var SchedulerService = &SchedulerServiceImpl{}
 
 // StartScheduler 启动定时任务调度器
+// 调度器在启动时创建，使用 main 中按 SERVER_TIMEZONE 设置后的时区
 func (s *SchedulerServiceImpl) StartScheduler() {
+	s.cron = cron.New(cron.WithLocation(time.Local))
+
 	// 添加数据库备份任务
 	backupSchedule := viper.GetString("BACKUP_SCHEDULE")
 	if backupSchedule == "" {
 		backupSchedule = "0 0 * * *" // 默认每天凌晨执行
 	}
 
-	s.cron.AddFunc(backupSchedule, func() {
-		log.Println("Starting database backup...")
+	s.addJob(backupSchedule, func() {
+		logger.Infof("Starting database backup...")
 
 		// 获取备份仓库ID
 		backupRepoID := viper.GetInt("BACKUP_REPO_ID")
 		if backupRepoID == 0 {
-			log.Println("Backup repository not configured")
+			logger.Warnf("Backup repository not configured")
 			return
 		}
 
 		// 执行备份
 		record, err := BackupService.CreateBackup(backupRepoID)
 		if err != nil {
-			log.Printf("Backup failed: %v\n", err)
+			logger.Errorf("Backup failed: %v", err)
 			return
 		}
 
-		log.Printf("Backup completed successfully: %s\n", record.BackupPath)
+		logger.Infof("Backup completed successfully: %s", record.BackupPath)
 
 		// 清理30天前的备份
 		if err := BackupService.CleanOldBackups(30); err != nil {
-			log.Printf("Failed to clean old backups: %v\n", err)
+			logger.Errorf("Failed to clean old backups: %v", err)
+		}
+	})
+
+	// 添加镜像补偿同步任务
+	mirrorSchedule := viper.GetString("MIRROR_SYNC_SCHEDULE")
+	if mirrorSchedule == "" {
+		mirrorSchedule = "*/10 * * * *" // 默认每10分钟执行
+	}
+
+	s.addJob(mirrorSchedule, func() {
+		if err := MirrorService.CatchUp(0); err != nil {
+			logger.Errorf("Mirror catch-up failed: %v", err)
+		}
+	})
+
+	// 添加链接健康检查任务
+	linkCheckSchedule := viper.GetString("LINK_CHECK_SCHEDULE")
+	if linkCheckSchedule == "" {
+		linkCheckSchedule = "0 3 * * *" // 默认每天凌晨3点执行
+	}
+
+	s.addJob(linkCheckSchedule, func() {
+		if err := LinkCheckService.Run(0); err != nil {
+			logger.Errorf("Link check failed: %v", err)
+		}
+	})
+
+	// 添加回收站清理任务
+	trashSchedule := viper.GetString("TRASH_PURGE_SCHEDULE")
+	if trashSchedule == "" {
+		trashSchedule = "0 4 * * *" // 默认每天凌晨4点执行
+	}
+
+	s.addJob(trashSchedule, func() {
+		if _, err := TrashService.Cleanup(); err != nil {
+			logger.Errorf("Trash cleanup failed: %v", err)
 		}
 	})
 
 	s.cron.Start()
 }
 
+// addJob 添加定时任务，执行时间格式错误时记录日志并跳过该任务
+func (s *SchedulerServiceImpl) addJob(schedule string, job func()) {
+	if _, err := s.cron.AddFunc(schedule, job); err != nil {
+		logger.Errorf("invalid schedule %q: %v", schedule, err)
+	}
+}
+
 // StopScheduler 停止定时任务调度器
 func (s *SchedulerServiceImpl) StopScheduler() {
 	if s.cron != nil {
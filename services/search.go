@@ -0,0 +1,356 @@
+package services
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"sort"
+	"strings"
+	"unicode"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"pichub.api/constants"
+	"pichub.api/infra/database"
+	"pichub.api/infra/logger"
+	"pichub.api/models"
+)
+
+type SearchServiceImpl struct{}
+
+var SearchService = &SearchServiceImpl{}
+
+var ErrEmptySearchQuery = errors.New("search query is required")
+
+// searchFields 建立全文索引的字段，与索引表中 FULLTEXT 索引的列保持一致
+var searchFields = []string{"filename", "title", "description", "tags", "albums", "exif"}
+
+// Search 全文搜索用户的文件，按相关度排序并返回匹配内容的高亮片段
+// filter 中的筛选条件同样生效，排序和游标参数被忽略
+func (s *SearchServiceImpl) Search(userID int, keyword string, filter models.FileFilter, page int, pageSize int) ([]models.SearchResult, int64, error) {
+	keyword = strings.TrimSpace(keyword)
+	terms := s.terms(keyword)
+	if len(terms) == 0 {
+		return nil, 0, ErrEmptySearchQuery
+	}
+
+	match := fmt.Sprintf("MATCH(%s) AGAINST(? IN NATURAL LANGUAGE MODE)", s.matchColumns())
+	query := database.DB.Model(&models.File{}).
+		Joins(fmt.Sprintf("JOIN %s si ON si.file_id = %s.id", models.FileSearchIndex{}.TableName(), fileTable())).
+		Where(fileTable()+".user_id = ?", userID).
+		Where(match, keyword)
+	query = FileService.applyFilter(query, userID, filter)
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var hits []struct {
+		ID    int
+		Score float64
+	}
+	offset := (page - 1) * pageSize
+	if err := query.Select(fmt.Sprintf("%s.id, %s AS score", fileTable(), match), keyword).
+		Order("score DESC, " + fileTable() + ".id DESC").
+		Offset(offset).Limit(pageSize).
+		Scan(&hits).Error; err != nil {
+		return nil, 0, err
+	}
+
+	results := []models.SearchResult{}
+	if len(hits) == 0 {
+		return results, total, nil
+	}
+
+	ids := make([]int, 0, len(hits))
+	for _, hit := range hits {
+		ids = append(ids, hit.ID)
+	}
+
+	var files []models.File
+	if err := database.DB.Where("id IN ?", ids).Find(&files).Error; err != nil {
+		return nil, 0, err
+	}
+	var docs []models.FileSearchIndex
+	if err := database.DB.Where("file_id IN ?", ids).Find(&docs).Error; err != nil {
+		return nil, 0, err
+	}
+
+	responses := map[int]models.FileResponse{}
+	for _, item := range FileService.ToResponses(files) {
+		responses[item.ID] = item
+	}
+	docMap := map[int]models.FileSearchIndex{}
+	for _, doc := range docs {
+		docMap[doc.FileID] = doc
+	}
+
+	for _, hit := range hits {
+		item, ok := responses[hit.ID]
+		if !ok {
+			continue
+		}
+		results = append(results, models.SearchResult{
+			FileResponse: item,
+			Score:        hit.Score,
+			Highlights:   s.highlights(docMap[hit.ID], terms),
+		})
+	}
+	return results, total, nil
+}
+
+// Refresh 重建文件的索引，失败只记录日志，用于文件、标签和相册变化后
+func (s *SearchServiceImpl) Refresh(fileIDs ...int) {
+	if err := s.Reindex(fileIDs); err != nil {
+		logger.Warnf("refresh search index of files %v failed: %v", fileIDs, err)
+	}
+}
+
+// RefreshAlbum 重建相册中所有文件的索引
+func (s *SearchServiceImpl) RefreshAlbum(albumID int) {
+	var fileIDs []int
+	if err := database.DB.Model(&models.AlbumFile{}).Where("album_id = ?", albumID).Pluck("file_id", &fileIDs).Error; err != nil {
+		logger.Warnf("refresh search index of album %d failed: %v", albumID, err)
+		return
+	}
+	s.Refresh(fileIDs...)
+}
+
+// Reindex 根据文件当前的文件名、标题、描述、标签、相册和 EXIF 重建索引
+func (s *SearchServiceImpl) Reindex(fileIDs []int) error {
+	if len(fileIDs) == 0 {
+		return nil
+	}
+
+	var files []models.File
+	if err := database.DB.Where("id IN ?", fileIDs).Find(&files).Error; err != nil {
+		return err
+	}
+	if len(files) == 0 {
+		return nil
+	}
+
+	ids := make([]int, 0, len(files))
+	for _, file := range files {
+		ids = append(ids, file.ID)
+	}
+	tags := TagService.FileTags(ids)
+	albums, err := s.fileAlbums(ids)
+	if err != nil {
+		return err
+	}
+
+	docs := make([]models.FileSearchIndex, 0, len(files))
+	for _, file := range files {
+		docs = append(docs, models.FileSearchIndex{
+			FileID:      file.ID,
+			UserID:      file.UserID,
+			Filename:    file.RawFilename,
+			Title:       file.Title,
+			Description: file.Description,
+			Tags:        strings.Join(tags[file.ID], " "),
+			Albums:      strings.Join(albums[file.ID], " "),
+			Exif:        s.exifText(file.Exif),
+		})
+	}
+
+	return database.DB.Clauses(clause.OnConflict{UpdateAll: true}).Create(&docs).Error
+}
+
+// Rebuild 重建用户所有文件的索引，返回处理的文件数
+func (s *SearchServiceImpl) Rebuild(userID int) (int, error) {
+	indexed := 0
+	lastID := 0
+	for {
+		var ids []int
+		if err := database.DB.Model(&models.File{}).
+			Where("user_id = ? AND id > ?", userID, lastID).
+			Order("id ASC").Limit(constants.SearchReindexBatch).
+			Pluck("id", &ids).Error; err != nil {
+			return indexed, err
+		}
+		if len(ids) == 0 {
+			return indexed, nil
+		}
+		if err := s.Reindex(ids); err != nil {
+			return indexed, err
+		}
+		indexed += len(ids)
+		lastID = ids[len(ids)-1]
+	}
+}
+
+// Backfill 为所有用户还没有索引的文件建立索引，返回处理的文件数
+// 用于全文搜索上线前上传的文件，服务启动时在后台执行
+func (s *SearchServiceImpl) Backfill() (int, error) {
+	indexed := 0
+	lastID := 0
+	for {
+		var ids []int
+		if err := database.DB.Model(&models.File{}).
+			Joins(fmt.Sprintf("LEFT JOIN %s si ON si.file_id = %s.id", models.FileSearchIndex{}.TableName(), fileTable())).
+			Where(fileTable()+".id > ? AND si.file_id IS NULL", lastID).
+			Order(fileTable()+".id ASC").Limit(constants.SearchReindexBatch).
+			Pluck(fileTable()+".id", &ids).Error; err != nil {
+			return indexed, err
+		}
+		if len(ids) == 0 {
+			return indexed, nil
+		}
+		if err := s.Reindex(ids); err != nil {
+			return indexed, err
+		}
+		indexed += len(ids)
+		lastID = ids[len(ids)-1]
+	}
+}
+
+// Remove 删除文件的索引
+func (s *SearchServiceImpl) Remove(fileID int) error {
+	return database.DB.Where("file_id = ?", fileID).Delete(&models.FileSearchIndex{}).Error
+}
+
+// fileAlbums 批量获取文件所在相册的名称
+func (s *SearchServiceImpl) fileAlbums(fileIDs []int) (map[int][]string, error) {
+	var rows []struct {
+		FileID int
+		Name   string
+	}
+	if err := database.DB.Table(database.DB.NamingStrategy.TableName("AlbumFile")+" af").
+		Select("af.file_id, a.name").
+		Joins(fmt.Sprintf("JOIN %s a ON a.id = af.album_id", database.DB.NamingStrategy.TableName("Album"))).
+		Where("af.file_id IN ?", fileIDs).
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	result := map[int][]string{}
+	for _, row := range rows {
+		result[row.FileID] = append(result[row.FileID], row.Name)
+	}
+	return result, nil
+}
+
+// exifText 将 EXIF 字段值按字段名顺序拼接为索引文本
+func (s *SearchServiceImpl) exifText(raw string) string {
+	if raw == "" {
+		return ""
+	}
+	var fields map[string]string
+	if err := json.Unmarshal([]byte(raw), &fields); err != nil {
+		return ""
+	}
+
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	values := make([]string, 0, len(keys))
+	for _, key := range keys {
+		values = append(values, fields[key])
+	}
+	return strings.Join(values, " ")
+}
+
+func (s *SearchServiceImpl) matchColumns() string {
+	columns := make([]string, 0, len(searchFields))
+	for _, field := range searchFields {
+		columns = append(columns, "si."+field)
+	}
+	return strings.Join(columns, ", ")
+}
+
+// terms 拆分搜索词用于高亮，去掉全文搜索的运算符
+func (s *SearchServiceImpl) terms(keyword string) []string {
+	seen := map[string]bool{}
+	var terms []string
+	for _, term := range strings.FieldsFunc(strings.ToLower(keyword), func(r rune) bool {
+		return unicode.IsSpace(r) || strings.ContainsRune(`+-<>()~*"@`, r)
+	}) {
+		if !seen[term] {
+			seen[term] = true
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
+
+// highlights 生成各字段中匹配内容的高亮片段
+func (s *SearchServiceImpl) highlights(doc models.FileSearchIndex, terms []string) []models.SearchHighlight {
+	values := map[string]string{
+		"filename":    doc.Filename,
+		"title":       doc.Title,
+		"description": doc.Description,
+		"tags":        doc.Tags,
+		"albums":      doc.Albums,
+		"exif":        doc.Exif,
+	}
+
+	highlights := []models.SearchHighlight{}
+	for _, field := range searchFields {
+		if snippet, ok := s.highlight(values[field], terms); ok {
+			highlights = append(highlights, models.SearchHighlight{Field: field, Snippet: snippet})
+		}
+	}
+	return highlights
+}
+
+// highlight 截取第一个匹配位置附近的内容，并用 <mark> 标记所有匹配的搜索词
+func (s *SearchServiceImpl) highlight(text string, terms []string) (string, bool) {
+	runes := []rune(text)
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	marked := make([]bool, len(runes))
+	first := -1
+	for _, term := range terms {
+		target := []rune(term)
+		for i := 0; i+len(target) <= len(lower); i++ {
+			if string(lower[i:i+len(target)]) != term {
+				continue
+			}
+			for j := i; j < i+len(target); j++ {
+				marked[j] = true
+			}
+			if first < 0 || i < first {
+				first = i
+			}
+		}
+	}
+	if first < 0 {
+		return "", false
+	}
+
+	start := first - constants.SearchSnippetRadius
+	if start < 0 {
+		start = 0
+	}
+	end := first + constants.SearchSnippetRadius*2
+	if end > len(runes) {
+		end = len(runes)
+	}
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	for i := start; i < end; i++ {
+		if marked[i] && (i == start || !marked[i-1]) {
+			b.WriteString("<mark>")
+		}
+		b.WriteString(html.EscapeString(string(runes[i])))
+		if marked[i] && (i+1 == end || !marked[i+1]) {
+			b.WriteString("</mark>")
+		}
+	}
+	if end < len(runes) {
+		b.WriteString("…")
+	}
+	return b.String(), true
+}
@@ -0,0 +1,285 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"pichub.api/config"
+	"pichub.api/constants"
+	"pichub.api/infra/database"
+	"pichub.api/infra/logger"
+	"pichub.api/models"
+)
+
+type ShareServiceImpl struct{}
+
+var ShareService = &ShareServiceImpl{}
+
+var (
+	ErrShareNotFound         = errors.New("share not found")
+	ErrShareRevoked          = errors.New("share has been revoked")
+	ErrShareExpired          = errors.New("share has expired")
+	ErrSharePasswordRequired = errors.New("password required")
+	ErrSharePasswordInvalid  = errors.New("invalid password")
+	ErrShareLimitReached     = errors.New("download limit reached")
+)
+
+// Create 创建分享链接
+func (s *ShareServiceImpl) Create(userID int, req models.CreateShareRequest) (*models.Share, error) {
+	if req.TargetType == "" {
+		req.TargetType = models.ShareTargetFile
+	}
+	// 默认代理返回，避免暴露文件的永久地址
+	if req.Mode == "" {
+		req.Mode = models.ShareModeProxy
+	}
+	if req.ExpiresIn > constants.MaxShareExpiresIn {
+		return nil, fmt.Errorf("expires_in must not exceed %d", constants.MaxShareExpiresIn)
+	}
+
+	if req.TargetType == models.ShareTargetAlbum {
+		if _, err := AlbumService.GetAlbum(userID, req.TargetID); err != nil {
+			return nil, err
+		}
+	} else if _, err := FileService.GetFile(userID, req.TargetID); err != nil {
+		return nil, errors.New("file not found")
+	}
+
+	token, err := s.generateToken()
+	if err != nil {
+		return nil, err
+	}
+
+	share := &models.Share{
+		UserID:       userID,
+		Token:        token,
+		TargetType:   req.TargetType,
+		TargetID:     req.TargetID,
+		Mode:         req.Mode,
+		MaxDownloads: req.MaxDownloads,
+	}
+	if req.Password != "" {
+		hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, err
+		}
+		share.PasswordHash = string(hash)
+	}
+	if req.ExpiresIn > 0 {
+		expiresAt := time.Now().Add(time.Duration(req.ExpiresIn) * time.Second)
+		share.ExpiresAt = &expiresAt
+	}
+
+	if err := database.DB.Create(share).Error; err != nil {
+		return nil, err
+	}
+	return share, nil
+}
+
+// List 分页获取用户的分享链接
+func (s *ShareServiceImpl) List(userID int, page int, pageSize int) ([]models.Share, int64, error) {
+	var total int64
+	var shares []models.Share
+
+	query := database.DB.Model(&models.Share{}).Where("user_id = ?", userID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	if err := query.Order("id DESC").Offset(offset).Limit(pageSize).Find(&shares).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return shares, total, nil
+}
+
+// Get 获取用户的分享链接
+func (s *ShareServiceImpl) Get(userID int, shareID int) (*models.Share, error) {
+	var share models.Share
+	if err := database.DB.Where("id = ? AND user_id = ?", shareID, userID).First(&share).Error; err != nil {
+		return nil, ErrShareNotFound
+	}
+	return &share, nil
+}
+
+// Revoke 撤销分享链接，撤销后访问记录仍然保留
+func (s *ShareServiceImpl) Revoke(userID int, shareID int) error {
+	share, err := s.Get(userID, shareID)
+	if err != nil {
+		return err
+	}
+	if share.RevokedAt != nil {
+		return nil
+	}
+	return database.DB.Model(share).Update("revoked_at", time.Now()).Error
+}
+
+// ListLogs 分页获取分享链接的访问记录
+func (s *ShareServiceImpl) ListLogs(userID int, shareID int, page int, pageSize int) ([]models.ShareAccessLog, int64, error) {
+	if _, err := s.Get(userID, shareID); err != nil {
+		return nil, 0, err
+	}
+
+	var total int64
+	var logs []models.ShareAccessLog
+
+	query := database.DB.Model(&models.ShareAccessLog{}).Where("share_id = ?", shareID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	if err := query.Order("id DESC").Offset(offset).Limit(pageSize).Find(&logs).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return logs, total, nil
+}
+
+// ToResponses 构建分享链接响应
+func (s *ShareServiceImpl) ToResponses(shares []models.Share) []models.ShareResponse {
+	response := []models.ShareResponse{}
+	for _, share := range shares {
+		response = append(response, models.ShareResponse{
+			Share:            share,
+			URL:              s.BuildURL(share.Token),
+			PasswordRequired: share.PasswordHash != "",
+			Active:           share.IsActive(),
+		})
+	}
+	return response
+}
+
+// BuildURL 生成分享链接的公开地址
+func (s *ShareServiceImpl) BuildURL(token string) string {
+	return fmt.Sprintf("%s/api/v1/s/%s", config.Config.Server.GetFrontendUrl(), token)
+}
+
+// Find 根据 token 查找分享链接，只校验撤销和有效期，不计入下载次数
+func (s *ShareServiceImpl) Find(token string) (*models.Share, error) {
+	var share models.Share
+	if err := database.DB.Where("token = ?", token).First(&share).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrShareNotFound
+		}
+		return nil, err
+	}
+	if share.RevokedAt != nil {
+		return &share, ErrShareRevoked
+	}
+	if share.ExpiresAt != nil && time.Now().After(*share.ExpiresAt) {
+		return &share, ErrShareExpired
+	}
+	return &share, nil
+}
+
+// Open 校验分享链接和访问密码，失败的访问同样写入访问记录
+// visitor 携带访问者的 IP、User-Agent 和来源
+func (s *ShareServiceImpl) Open(token string, password string, visitor models.ShareAccessLog) (*models.Share, error) {
+	share, err := s.Find(token)
+	if share == nil {
+		return nil, err
+	}
+
+	if err == nil && share.PasswordHash != "" {
+		if password == "" {
+			err = ErrSharePasswordRequired
+		} else if bcrypt.CompareHashAndPassword([]byte(share.PasswordHash), []byte(password)) != nil {
+			err = ErrSharePasswordInvalid
+		}
+	}
+
+	if err != nil && err != ErrSharePasswordRequired {
+		s.log(share, 0, visitor, s.accessResult(err))
+	}
+	return share, err
+}
+
+// SharedFile 获取分享中的文件，相册分享只能访问相册中的文件
+func (s *ShareServiceImpl) SharedFile(share *models.Share, fileID int) (*models.File, error) {
+	if share.TargetType != models.ShareTargetAlbum {
+		if fileID != 0 && fileID != share.TargetID {
+			return nil, errors.New("file not found")
+		}
+		return FileService.GetFile(share.UserID, share.TargetID)
+	}
+
+	var count int64
+	if err := database.DB.Model(&models.AlbumFile{}).Where("album_id = ? AND file_id = ?", share.TargetID, fileID).
+		Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, errors.New("file not found")
+	}
+	return FileService.GetFile(share.UserID, fileID)
+}
+
+// FileURL 生成分享中单个文件的下载地址
+func (s *ShareServiceImpl) FileURL(token string, fileID int) string {
+	return fmt.Sprintf("%s/files/%d", s.BuildURL(token), fileID)
+}
+
+// Consume 记录一次文件下载，超过最大下载次数时返回 ErrShareLimitReached
+func (s *ShareServiceImpl) Consume(share *models.Share, fileID int, visitor models.ShareAccessLog) error {
+	// 在同一条语句中判断和累加，避免并发下载超出限制
+	result := database.DB.Model(&models.Share{}).
+		Where("id = ? AND (max_downloads = 0 OR download_count < max_downloads)", share.ID).
+		UpdateColumn("download_count", gorm.Expr("download_count + 1"))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		s.log(share, fileID, visitor, models.ShareAccessLimitReached)
+		return ErrShareLimitReached
+	}
+
+	share.DownloadCount++
+	s.log(share, fileID, visitor, models.ShareAccessOK)
+	return nil
+}
+
+// log 写入访问记录，失败只记录日志不影响访问
+func (s *ShareServiceImpl) log(share *models.Share, fileID int, visitor models.ShareAccessLog, result string) {
+	visitor.ID = 0
+	visitor.ShareID = share.ID
+	visitor.FileID = fileID
+	visitor.Result = result
+	if len(visitor.UserAgent) > 500 {
+		visitor.UserAgent = visitor.UserAgent[:500]
+	}
+	if len(visitor.Referer) > 500 {
+		visitor.Referer = visitor.Referer[:500]
+	}
+
+	if err := database.DB.Create(&visitor).Error; err != nil {
+		logger.Errorf("save access log of share %d failed: %v", share.ID, err)
+	}
+}
+
+func (s *ShareServiceImpl) accessResult(err error) string {
+	switch err {
+	case ErrShareRevoked:
+		return models.ShareAccessRevoked
+	case ErrShareExpired:
+		return models.ShareAccessExpired
+	case ErrShareLimitReached:
+		return models.ShareAccessLimitReached
+	}
+	return models.ShareAccessPasswordInvalid
+}
+
+// generateToken 生成分享链接的随机 token
+func (s *ShareServiceImpl) generateToken() (string, error) {
+	buf := make([]byte, constants.ShareTokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
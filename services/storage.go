@@ -0,0 +1,247 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"pichub.api/constants"
+	"pichub.api/infra/database"
+	"pichub.api/infra/logger"
+	"pichub.api/models"
+	"pichub.api/pkg/utils"
+)
+
+type StorageServiceImpl struct{}
+
+var StorageService = &StorageServiceImpl{}
+
+// RepoSoftLimit GitHub 建议的单仓库大小上限，超过后仓库性能明显下降
+const RepoSoftLimit int64 = 1 << 30
+
+// RecordCreate 文件创建后累加用量
+func (s *StorageServiceImpl) RecordCreate(file *models.File) {
+	s.addUsage(database.DB, file.UserID, file.RepoID, file.Filetype, 1, int64(file.Filesize))
+}
+
+// RecordDelete 文件删除后扣减用量
+func (s *StorageServiceImpl) RecordDelete(file *models.File) {
+	s.addUsage(database.DB, file.UserID, file.RepoID, file.Filetype, -1, -int64(file.Filesize))
+}
+
+// addUsage 原子地更新用量，记录不存在时创建
+func (s *StorageServiceImpl) addUsage(tx *gorm.DB, userID int, repoID int, filetype uint8, count int64, size int64) {
+	usage := models.StorageUsage{
+		UserID:    userID,
+		RepoID:    repoID,
+		Filetype:  filetype,
+		FileCount: count,
+		TotalSize: size,
+	}
+
+	err := tx.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}, {Name: "repo_id"}, {Name: "filetype"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"file_count": gorm.Expr("GREATEST(file_count + ?, 0)", count),
+			"total_size": gorm.Expr("GREATEST(total_size + ?, 0)", size),
+		}),
+	}).Create(&usage).Error
+	if err != nil {
+		// 用量统计失败不影响文件操作，可通过 Recalculate 修正
+		logger.Errorf("update storage usage failed, user %d repo %d: %v", userID, repoID, err)
+	}
+}
+
+// Recalculate 根据文件表重新统计用户的用量，回收站中的文件在彻底删除前仍占用空间
+func (s *StorageServiceImpl) Recalculate(userID int) error {
+	var rows []models.StorageUsage
+	err := database.DB.Unscoped().Model(&models.File{}).
+		Select("user_id, repo_id, filetype, COUNT(*) AS file_count, COALESCE(SUM(filesize), 0) AS total_size").
+		Where("user_id = ?", userID).
+		Group("user_id, repo_id, filetype").
+		Scan(&rows).Error
+	if err != nil {
+		return err
+	}
+
+	return database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.StorageUsage{}).Error; err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		return tx.Create(&rows).Error
+	})
+}
+
+// ClearRepository 删除仓库时清理对应的用量记录
+func (s *StorageServiceImpl) ClearRepository(userID int, repoID int) error {
+	return database.DB.Where("user_id = ? AND repo_id = ?", userID, repoID).Delete(&models.StorageUsage{}).Error
+}
+
+// GetRepositoryUsage 获取单个仓库的用量
+func (s *StorageServiceImpl) GetRepositoryUsage(userID int, repoID int) (models.UsageStat, error) {
+	var stat models.UsageStat
+	err := database.DB.Model(&models.StorageUsage{}).
+		Select("COALESCE(SUM(file_count), 0) AS file_count, COALESCE(SUM(total_size), 0) AS total_size").
+		Where("user_id = ? AND repo_id = ?", userID, repoID).
+		Scan(&stat).Error
+	return stat, err
+}
+
+// GetUsage 获取用户用量，按文件类型和仓库分别汇总
+func (s *StorageServiceImpl) GetUsage(userID int) (*models.UserUsageResponse, error) {
+	var usages []models.StorageUsage
+	if err := database.DB.Where("user_id = ?", userID).Find(&usages).Error; err != nil {
+		return nil, err
+	}
+
+	repositories, err := RepositoryService.ListRepositories(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	quotas, err := s.ListQuotas(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &models.UserUsageResponse{
+		ByFiletype:   []models.FiletypeUsage{},
+		Repositories: []models.RepositoryUsage{},
+	}
+
+	byFiletype := map[uint8]*models.UsageStat{}
+	byRepo := map[int]*models.UsageStat{}
+	for _, usage := range usages {
+		response.Total.FileCount += usage.FileCount
+		response.Total.TotalSize += usage.TotalSize
+
+		if byFiletype[usage.Filetype] == nil {
+			byFiletype[usage.Filetype] = &models.UsageStat{}
+		}
+		byFiletype[usage.Filetype].FileCount += usage.FileCount
+		byFiletype[usage.Filetype].TotalSize += usage.TotalSize
+
+		if byRepo[usage.RepoID] == nil {
+			byRepo[usage.RepoID] = &models.UsageStat{}
+		}
+		byRepo[usage.RepoID].FileCount += usage.FileCount
+		byRepo[usage.RepoID].TotalSize += usage.TotalSize
+	}
+
+	for code := uint8(0); code <= 5; code++ {
+		if stat, ok := byFiletype[code]; ok {
+			response.ByFiletype = append(response.ByFiletype, models.FiletypeUsage{
+				Filetype:  code,
+				Name:      fileTypeNames[code],
+				UsageStat: *stat,
+			})
+		}
+	}
+
+	quotaByRepo := map[int]*models.StorageQuota{}
+	for i := range quotas {
+		quotaByRepo[quotas[i].RepoID] = &quotas[i]
+	}
+	response.Quota = quotaByRepo[0]
+
+	for _, repo := range repositories {
+		stat := byRepo[repo.ID]
+		if stat == nil {
+			stat = &models.UsageStat{}
+		}
+		response.Repositories = append(response.Repositories, models.RepositoryUsage{
+			RepoID:           repo.ID,
+			RepoName:         repo.RepoName,
+			Quota:            quotaByRepo[repo.ID],
+			UsageStat:        *stat,
+			SoftLimitPercent: float64(stat.TotalSize) * 100 / float64(RepoSoftLimit),
+		})
+	}
+
+	return response, nil
+}
+
+// CheckQuota 检查新增文件后是否会超出用户或仓库配额
+func (s *StorageServiceImpl) CheckQuota(userID int, repoID int, size int64) error {
+	quotas, err := s.ListQuotas(userID)
+	if err != nil {
+		return err
+	}
+
+	for _, quota := range quotas {
+		if quota.RepoID != 0 && quota.RepoID != repoID {
+			continue
+		}
+
+		var stat models.UsageStat
+		query := database.DB.Model(&models.StorageUsage{}).
+			Select("COALESCE(SUM(file_count), 0) AS file_count, COALESCE(SUM(total_size), 0) AS total_size").
+			Where("user_id = ?", userID)
+		if quota.RepoID != 0 {
+			query = query.Where("repo_id = ?", quota.RepoID)
+		}
+		if err := query.Scan(&stat).Error; err != nil {
+			return err
+		}
+
+		scope := utils.If(quota.RepoID == 0, "user", "repository")
+		if quota.MaxSize > 0 && stat.TotalSize+size > quota.MaxSize {
+			return &UploadRejectedError{
+				Code: constants.ErrCodeQuotaExceeded,
+				Reason: fmt.Sprintf("%s storage quota exceeded: %s used of %s", scope,
+					utils.FriendlyFileSize(stat.TotalSize), utils.FriendlyFileSize(quota.MaxSize)),
+			}
+		}
+		if quota.MaxFiles > 0 && stat.FileCount+1 > quota.MaxFiles {
+			return &UploadRejectedError{
+				Code:   constants.ErrCodeQuotaExceeded,
+				Reason: fmt.Sprintf("%s file count quota exceeded: %d of %d files", scope, stat.FileCount, quota.MaxFiles),
+			}
+		}
+	}
+
+	return nil
+}
+
+// ListQuotas 获取用户的配额设置
+func (s *StorageServiceImpl) ListQuotas(userID int) ([]models.StorageQuota, error) {
+	var quotas []models.StorageQuota
+	if err := database.DB.Where("user_id = ?", userID).Order("repo_id ASC").Find(&quotas).Error; err != nil {
+		return nil, err
+	}
+	return quotas, nil
+}
+
+// SetQuota 设置用户或仓库配额，已存在时更新
+func (s *StorageServiceImpl) SetQuota(req models.SetStorageQuotaRequest) (*models.StorageQuota, error) {
+	if req.RepoID != 0 {
+		if _, err := RepositoryService.GetRepository(req.UserID, req.RepoID); err != nil {
+			return nil, errors.New("repository not found")
+		}
+	}
+
+	var quota models.StorageQuota
+	err := database.DB.Where("user_id = ? AND repo_id = ?", req.UserID, req.RepoID).First(&quota).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	quota.UserID = req.UserID
+	quota.RepoID = req.RepoID
+	quota.MaxSize = req.MaxSize
+	quota.MaxFiles = req.MaxFiles
+
+	if err := database.DB.Save(&quota).Error; err != nil {
+		return nil, err
+	}
+	return &quota, nil
+}
+
+// DeleteQuota 删除配额
+func (s *StorageServiceImpl) DeleteQuota(quotaID int) error {
+	return database.DB.Delete(&models.StorageQuota{}, quotaID).Error
+}
@@ -0,0 +1,243 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"pichub.api/constants"
+	"pichub.api/helpers"
+	"pichub.api/infra/database"
+	"pichub.api/models"
+)
+
+type TagServiceImpl struct{}
+
+var TagService = &TagServiceImpl{}
+
+// TagFiles 为文件添加标签，不存在的标签自动创建，返回新增的关联数量
+func (s *TagServiceImpl) TagFiles(userID int, fileIDs []int, names []string) (int64, error) {
+	names = s.NormalizeNames(names)
+	if len(names) == 0 {
+		return 0, errors.New("tags are required")
+	}
+
+	fileIDs, err := s.ownedFiles(userID, fileIDs)
+	if err != nil {
+		return 0, err
+	}
+
+	var added int64
+	err = database.DB.Transaction(func(tx *gorm.DB) error {
+		tags := make([]models.Tag, 0, len(names))
+		for _, name := range names {
+			tags = append(tags, models.Tag{UserID: userID, Name: name})
+		}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&tags).Error; err != nil {
+			return err
+		}
+
+		var tagIDs []int
+		if err := tx.Model(&models.Tag{}).Where("user_id = ? AND name IN ?", userID, names).Pluck("id", &tagIDs).Error; err != nil {
+			return err
+		}
+
+		var links []models.FileTag
+		for _, fileID := range fileIDs {
+			for _, tagID := range tagIDs {
+				links = append(links, models.FileTag{FileID: fileID, TagID: tagID})
+			}
+		}
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&links)
+		added = result.RowsAffected
+		return result.Error
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	SearchService.Refresh(fileIDs...)
+	return added, nil
+}
+
+// UntagFiles 移除文件的标签，不再被使用的标签一并删除
+func (s *TagServiceImpl) UntagFiles(userID int, fileIDs []int, names []string) error {
+	names = s.NormalizeNames(names)
+	if len(names) == 0 {
+		return errors.New("tags are required")
+	}
+
+	var tagIDs []int
+	if err := database.DB.Model(&models.Tag{}).Where("user_id = ? AND name IN ?", userID, names).Pluck("id", &tagIDs).Error; err != nil {
+		return err
+	}
+	if len(tagIDs) == 0 {
+		return nil
+	}
+
+	fileIDs, err := s.ownedFiles(userID, fileIDs)
+	if err != nil {
+		return err
+	}
+
+	if err := database.DB.Where("file_id IN ? AND tag_id IN ?", fileIDs, tagIDs).Delete(&models.FileTag{}).Error; err != nil {
+		return err
+	}
+	SearchService.Refresh(fileIDs...)
+	return s.cleanUnused(userID)
+}
+
+// DeleteTag 删除标签及其所有文件关联
+func (s *TagServiceImpl) DeleteTag(userID int, tagID int) error {
+	var tag models.Tag
+	if err := database.DB.Where("id = ? AND user_id = ?", tagID, userID).First(&tag).Error; err != nil {
+		return errors.New("tag not found")
+	}
+
+	var fileIDs []int
+	if err := database.DB.Model(&models.FileTag{}).Where("tag_id = ?", tag.ID).Pluck("file_id", &fileIDs).Error; err != nil {
+		return err
+	}
+
+	if err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("tag_id = ?", tag.ID).Delete(&models.FileTag{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&tag).Error
+	}); err != nil {
+		return err
+	}
+
+	SearchService.Refresh(fileIDs...)
+	return nil
+}
+
+// Counts 获取用户的标签及各标签的文件数量，按文件数量降序
+func (s *TagServiceImpl) Counts(userID int) ([]models.TagCount, error) {
+	counts := []models.TagCount{}
+	err := s.countQuery(userID).Order("count DESC, t.name ASC").Scan(&counts).Error
+	return counts, err
+}
+
+// Suggest 按前缀推荐标签，常用的标签排在前面
+func (s *TagServiceImpl) Suggest(userID int, prefix string, limit int) ([]models.TagCount, error) {
+	prefix = strings.TrimSpace(prefix)
+	if limit <= 0 || limit > constants.MaxTagSuggestions {
+		limit = constants.MaxTagSuggestions
+	}
+
+	counts := []models.TagCount{}
+	query := s.countQuery(userID)
+	if prefix != "" {
+		query = query.Where("t.name LIKE ?", helpers.EscapeLike(prefix)+"%")
+	}
+	err := query.Order("count DESC, t.name ASC").Limit(limit).Scan(&counts).Error
+	return counts, err
+}
+
+// FileTags 批量获取文件的标签名
+func (s *TagServiceImpl) FileTags(fileIDs []int) map[int][]string {
+	result := map[int][]string{}
+	if len(fileIDs) == 0 {
+		return result
+	}
+
+	var rows []struct {
+		FileID int
+		Name   string
+	}
+	if err := database.DB.Table(fileTagTable()+" ft").
+		Select("ft.file_id, t.name").
+		Joins(fmt.Sprintf("JOIN %s t ON t.id = ft.tag_id", tagTable())).
+		Where("ft.file_id IN ?", fileIDs).
+		Order("t.name ASC").
+		Scan(&rows).Error; err != nil {
+		return result
+	}
+
+	for _, row := range rows {
+		result[row.FileID] = append(result[row.FileID], row.Name)
+	}
+	return result
+}
+
+// ApplyFilter 为文件查询添加标签筛选条件
+// and 要求文件包含全部标签，or 只需包含任一标签
+func (s *TagServiceImpl) ApplyFilter(query *gorm.DB, userID int, names []string, mode string) *gorm.DB {
+	names = s.NormalizeNames(names)
+	if len(names) == 0 {
+		return query
+	}
+
+	sub := database.DB.Table(fileTagTable()+" ft").
+		Select("ft.file_id").
+		Joins(fmt.Sprintf("JOIN %s t ON t.id = ft.tag_id", tagTable())).
+		Where("t.user_id = ? AND t.name IN ?", userID, names)
+	if mode != models.TagMatchAny {
+		sub = sub.Group("ft.file_id").Having("COUNT(DISTINCT ft.tag_id) = ?", len(names))
+	}
+
+	return query.Where(fileTable()+".id IN (?)", sub)
+}
+
+// RemoveFile 文件删除后清理标签关联
+func (s *TagServiceImpl) RemoveFile(userID int, fileID int) error {
+	if err := database.DB.Where("file_id = ?", fileID).Delete(&models.FileTag{}).Error; err != nil {
+		return err
+	}
+	return s.cleanUnused(userID)
+}
+
+// NormalizeNames 去除空白和重复的标签名，重复判断不区分大小写
+func (s *TagServiceImpl) NormalizeNames(names []string) []string {
+	seen := map[string]bool{}
+	result := []string{}
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		key := strings.ToLower(name)
+		if name == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		result = append(result, name)
+	}
+	return result
+}
+
+// countQuery 统计用户每个标签的文件数量
+func (s *TagServiceImpl) countQuery(userID int) *gorm.DB {
+	return database.DB.Table(tagTable()+" t").
+		Select("t.id, t.name, COUNT(ft.id) AS count").
+		Joins(fmt.Sprintf("LEFT JOIN %s ft ON ft.tag_id = t.id AND ft.file_id IN (?)", fileTagTable()),
+			database.DB.Model(&models.File{}).Select("id").Where("user_id = ?", userID)).
+		Where("t.user_id = ?", userID).
+		Group("t.id, t.name")
+}
+
+// ownedFiles 过滤出属于用户的文件
+func (s *TagServiceImpl) ownedFiles(userID int, fileIDs []int) ([]int, error) {
+	var owned []int
+	if err := database.DB.Model(&models.File{}).Where("id IN ? AND user_id = ?", fileIDs, userID).Pluck("id", &owned).Error; err != nil {
+		return nil, err
+	}
+	if len(owned) == 0 {
+		return nil, errors.New("file not found")
+	}
+	return owned, nil
+}
+
+// cleanUnused 删除用户不再关联任何文件的标签
+func (s *TagServiceImpl) cleanUnused(userID int) error {
+	used := database.DB.Model(&models.FileTag{}).Select("tag_id")
+	return database.DB.Where("user_id = ? AND id NOT IN (?)", userID, used).Delete(&models.Tag{}).Error
+}
+
+func tagTable() string {
+	return database.DB.NamingStrategy.TableName("Tag")
+}
+
+func fileTagTable() string {
+	return database.DB.NamingStrategy.TableName("FileTag")
+}
@@ -0,0 +1,153 @@
+package services
+
+import (
+	"errors"
+	"time"
+
+	"pichub.api/constants"
+	"pichub.api/infra/database"
+	"pichub.api/infra/logger"
+	"pichub.api/models"
+	"pichub.api/pkg/utils"
+)
+
+type TrashServiceImpl struct{}
+
+var TrashService = &TrashServiceImpl{}
+
+// GetRetentionDays 获取回收站的保留天数，可通过 file.trash_retention_days 配置
+func (s *TrashServiceImpl) GetRetentionDays(userID int) int {
+	value, err := ConfigService.Get("file", "trash_retention_days", userID)
+	if err != nil || utils.IsEmpty(value) {
+		value, _ = ConfigService.Get("file", "trash_retention_days", 0)
+	}
+
+	days := utils.ToInt(value, constants.DefaultTrashRetentionDays)
+	if days < 0 {
+		days = constants.DefaultTrashRetentionDays
+	}
+	return days
+}
+
+// List 分页获取回收站中的文件，最近删除的在前
+func (s *TrashServiceImpl) List(userID int, page int, pageSize int) ([]models.File, int64, error) {
+	var total int64
+	var files []models.File
+
+	query := database.DB.Unscoped().Model(&models.File{}).Where("user_id = ? AND deleted_at IS NOT NULL", userID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	offset := (page - 1) * pageSize
+	if err := query.Order("deleted_at DESC, id DESC").Offset(offset).Limit(pageSize).Find(&files).Error; err != nil {
+		return nil, 0, err
+	}
+
+	return files, total, nil
+}
+
+// Restore 从回收站恢复文件，返回恢复的文件数
+func (s *TrashServiceImpl) Restore(userID int, fileIDs []int) (int64, error) {
+	result := database.DB.Unscoped().Model(&models.File{}).
+		Where("id IN ? AND user_id = ? AND deleted_at IS NOT NULL", fileIDs, userID).
+		Update("deleted_at", nil)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if result.RowsAffected > 0 {
+		SearchService.Refresh(fileIDs...)
+	}
+	return result.RowsAffected, nil
+}
+
+// Delete 彻底删除回收站中的指定文件
+func (s *TrashServiceImpl) Delete(userID int, fileIDs []int) (int, error) {
+	var files []models.File
+	if err := database.DB.Unscoped().
+		Where("id IN ? AND user_id = ? AND deleted_at IS NOT NULL", fileIDs, userID).
+		Find(&files).Error; err != nil {
+		return 0, err
+	}
+	if len(files) == 0 {
+		return 0, errors.New("file not found in trash")
+	}
+	return s.destroy(files)
+}
+
+// Empty 清空用户的回收站
+func (s *TrashServiceImpl) Empty(userID int) (int, error) {
+	deleted := 0
+	for {
+		var files []models.File
+		if err := database.DB.Unscoped().
+			Where("user_id = ? AND deleted_at IS NOT NULL", userID).
+			Order("id ASC").Limit(constants.TrashPurgeBatch).
+			Find(&files).Error; err != nil {
+			return deleted, err
+		}
+		if len(files) == 0 {
+			return deleted, nil
+		}
+
+		n, err := s.destroy(files)
+		deleted += n
+		if err != nil {
+			return deleted, err
+		}
+	}
+}
+
+// Cleanup 彻底删除超过保留期的文件，由定时任务调用
+func (s *TrashServiceImpl) Cleanup() (int, error) {
+	var userIDs []int
+	if err := database.DB.Unscoped().Model(&models.File{}).
+		Where("deleted_at IS NOT NULL").
+		Distinct("user_id").Pluck("user_id", &userIDs).Error; err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for _, userID := range userIDs {
+		cutoff := time.Now().AddDate(0, 0, -s.GetRetentionDays(userID))
+		lastID := 0
+		for {
+			var files []models.File
+			if err := database.DB.Unscoped().
+				Where("user_id = ? AND deleted_at IS NOT NULL AND deleted_at < ? AND id > ?", userID, cutoff, lastID).
+				Order("id ASC").Limit(constants.TrashPurgeBatch).
+				Find(&files).Error; err != nil {
+				return deleted, err
+			}
+			if len(files) == 0 {
+				break
+			}
+			lastID = files[len(files)-1].ID
+
+			// 单个文件失败不影响其他文件，下次执行时重试
+			n, err := s.destroy(files)
+			deleted += n
+			if err != nil {
+				logger.Warnf("purge trash of user %d failed: %v", userID, err)
+			}
+		}
+	}
+
+	logger.Infof("trash cleanup finished, %d files deleted", deleted)
+	return deleted, nil
+}
+
+// destroy 逐个彻底删除文件，返回成功删除的数量和最后一个错误
+func (s *TrashServiceImpl) destroy(files []models.File) (int, error) {
+	deleted := 0
+	var lastErr error
+	for i := range files {
+		if err := FileService.DestroyFile(&files[i]); err != nil {
+			logger.Warnf("delete file %d from trash failed: %v", files[i].ID, err)
+			lastErr = err
+			continue
+		}
+		deleted++
+	}
+	return deleted, lastErr
+}
@@ -0,0 +1,211 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"pichub.api/constants"
+	"pichub.api/infra/database"
+	"pichub.api/models"
+	"pichub.api/pkg/utils"
+)
+
+type UploadPolicyServiceImpl struct{}
+
+var UploadPolicyService = &UploadPolicyServiceImpl{}
+
+// 文件类型代码对应的名称，与 utils.DetermineFileType 保持一致
+var fileTypeNames = map[uint8]string{
+	0: "unknown",
+	1: "image",
+	2: "video",
+	3: "audio",
+	4: "text",
+	5: "other",
+}
+
+// ListPolicies 获取对用户可见的策略：系统策略和用户自己的策略
+func (s *UploadPolicyServiceImpl) ListPolicies(userID int) ([]models.UploadPolicy, error) {
+	var policies []models.UploadPolicy
+	if err := database.DB.Where("user_id = ? OR user_id = 0", userID).Order("user_id ASC, repo_id ASC, id ASC").Find(&policies).Error; err != nil {
+		return nil, err
+	}
+	return policies, nil
+}
+
+// GetPolicy 获取策略
+func (s *UploadPolicyServiceImpl) GetPolicy(policyID int) (*models.UploadPolicy, error) {
+	var policy models.UploadPolicy
+	if err := database.DB.First(&policy, policyID).Error; err != nil {
+		return nil, err
+	}
+	return &policy, nil
+}
+
+// CreatePolicy 创建策略
+func (s *UploadPolicyServiceImpl) CreatePolicy(req models.UploadPolicyRequest) (*models.UploadPolicy, error) {
+	if err := s.validateScope(req.UserID, req.RepoID); err != nil {
+		return nil, err
+	}
+
+	policy := &models.UploadPolicy{UserID: req.UserID, RepoID: req.RepoID}
+	s.fillPolicy(policy, req)
+
+	if err := database.DB.Create(policy).Error; err != nil {
+		return nil, err
+	}
+	return policy, nil
+}
+
+// UpdatePolicy 更新策略，策略归属不可修改
+func (s *UploadPolicyServiceImpl) UpdatePolicy(policy *models.UploadPolicy, req models.UploadPolicyRequest) error {
+	if req.RepoID != policy.RepoID {
+		if err := s.validateScope(policy.UserID, req.RepoID); err != nil {
+			return err
+		}
+		policy.RepoID = req.RepoID
+	}
+
+	s.fillPolicy(policy, req)
+	return database.DB.Save(policy).Error
+}
+
+// DeletePolicy 删除策略
+func (s *UploadPolicyServiceImpl) DeletePolicy(policy *models.UploadPolicy) error {
+	return database.DB.Delete(policy).Error
+}
+
+// CheckSize 在读取文件内容之前，根据声明的大小提前检查
+func (s *UploadPolicyServiceImpl) CheckSize(userID int, repoID int, size int64) error {
+	policies, err := s.getApplicable(userID, repoID)
+	if err != nil {
+		return err
+	}
+
+	for _, policy := range policies {
+		if err := s.checkSize(&policy, size); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MaxFileSize 获取对本次上传生效的最小单文件大小限制，0 表示不限制
+func (s *UploadPolicyServiceImpl) MaxFileSize(userID int, repoID int) int64 {
+	policies, err := s.getApplicable(userID, repoID)
+	if err != nil {
+		return 0
+	}
+
+	var limit int64
+	for _, policy := range policies {
+		if size := int64(policy.MaxFileSize); size > 0 && (limit == 0 || size < limit) {
+			limit = size
+		}
+	}
+	return limit
+}
+
+// Check 检查上传文件是否满足系统、用户和仓库的所有策略
+// width/height 为 0 时跳过尺寸检查（非图片或无法解析尺寸）
+func (s *UploadPolicyServiceImpl) Check(userID int, repoID int, fileType uint8, size int64, width int, height int) error {
+	policies, err := s.getApplicable(userID, repoID)
+	if err != nil {
+		return err
+	}
+
+	for _, policy := range policies {
+		if allowed := policy.AllowedTypeList(); len(allowed) > 0 {
+			matched := false
+			for _, t := range allowed {
+				if t == fileType {
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				names := make([]string, 0, len(allowed))
+				for _, t := range allowed {
+					names = append(names, fileTypeNames[t])
+				}
+				return &UploadRejectedError{
+					Code:   constants.ErrCodeFileTypeNotAllowed,
+					Reason: fmt.Sprintf("file type %s is not allowed, allowed types: %s", fileTypeNames[fileType], strings.Join(names, ", ")),
+				}
+			}
+		}
+
+		if err := s.checkSize(&policy, size); err != nil {
+			return err
+		}
+
+		if (policy.MaxWidth > 0 && uint(width) > policy.MaxWidth) || (policy.MaxHeight > 0 && uint(height) > policy.MaxHeight) {
+			return &UploadRejectedError{
+				Code: constants.ErrCodeImageTooLarge,
+				Reason: fmt.Sprintf("image dimensions %dx%d exceed the limit of %s",
+					width, height, s.formatDimensions(policy.MaxWidth, policy.MaxHeight)),
+			}
+		}
+	}
+
+	return nil
+}
+
+func (s *UploadPolicyServiceImpl) checkSize(policy *models.UploadPolicy, size int64) error {
+	if policy.MaxFileSize > 0 && size > int64(policy.MaxFileSize) {
+		return &UploadRejectedError{
+			Code: constants.ErrCodeFileTooLarge,
+			Reason: fmt.Sprintf("file size %s exceeds the limit of %s",
+				utils.FriendlyFileSize(size), utils.FriendlyFileSize(int64(policy.MaxFileSize))),
+		}
+	}
+	return nil
+}
+
+// getApplicable 获取对本次上传生效的策略，顺序为系统、用户、仓库
+func (s *UploadPolicyServiceImpl) getApplicable(userID int, repoID int) ([]models.UploadPolicy, error) {
+	var policies []models.UploadPolicy
+	err := database.DB.
+		Where("(user_id = 0 AND repo_id = 0) OR (user_id = ? AND (repo_id = 0 OR repo_id = ?))", userID, repoID).
+		Order("user_id ASC, repo_id ASC").
+		Find(&policies).Error
+	if err != nil {
+		return nil, err
+	}
+	return policies, nil
+}
+
+// validateScope 仓库级策略必须属于该用户的仓库，系统策略不能指定仓库
+func (s *UploadPolicyServiceImpl) validateScope(userID int, repoID int) error {
+	if repoID == 0 {
+		return nil
+	}
+	if userID == 0 {
+		return errors.New("system policy cannot be bound to a repository")
+	}
+	if _, err := RepositoryService.GetRepository(userID, repoID); err != nil {
+		return errors.New("repository not found")
+	}
+	return nil
+}
+
+func (s *UploadPolicyServiceImpl) fillPolicy(policy *models.UploadPolicy, req models.UploadPolicyRequest) {
+	types := make([]string, 0, len(req.AllowedTypes))
+	for _, t := range req.AllowedTypes {
+		types = append(types, strconv.Itoa(int(t)))
+	}
+
+	policy.AllowedTypes = strings.Join(types, ",")
+	policy.MaxFileSize = req.MaxFileSize
+	policy.MaxWidth = req.MaxWidth
+	policy.MaxHeight = req.MaxHeight
+	policy.Remark = req.Remark
+}
+
+func (s *UploadPolicyServiceImpl) formatDimensions(width uint, height uint) string {
+	w := utils.If(width > 0, strconv.Itoa(int(width)), "any")
+	h := utils.If(height > 0, strconv.Itoa(int(height)), "any")
+	return w + "x" + h
+}
@@ -0,0 +1,120 @@
+package services
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+
+	"pichub.api/infra/database"
+	"pichub.api/models"
+	"pichub.api/pkg/utils"
+)
+
+type WatermarkServiceImpl struct{}
+
+var WatermarkService = &WatermarkServiceImpl{}
+
+const (
+	WatermarkTypeText  = "text"
+	WatermarkTypeImage = "image"
+)
+
+// GetConfig 读取用户的水印配置，用户未配置时使用系统配置
+func (s *WatermarkServiceImpl) GetConfig(userID int) (*models.WatermarkConfig, error) {
+	values, err := ConfigService.GetByType("watermark", userID)
+	if err != nil {
+		return nil, err
+	}
+	ownerID := userID
+	if len(values) == 0 && userID != 0 {
+		if values, err = ConfigService.GetByType("watermark", 0); err != nil {
+			return nil, err
+		}
+		ownerID = 0
+	}
+
+	config := &models.WatermarkConfig{
+		Enabled:  utils.ToBool(values["enabled"], false),
+		Type:     utils.ToString(values["type"]),
+		Text:     utils.ToString(values["text"]),
+		Color:    utils.ToString(values["color"]),
+		FileID:   utils.ToInt(values["file_id"], 0),
+		Position: utils.ToString(values["position"]),
+		Opacity:  utils.ToFloat(values["opacity"], 0.5),
+		Scale:    utils.ToFloat(values["scale"], 0.2),
+		Margin:   utils.ToInt(values["margin"], 10),
+		OwnerID:  ownerID,
+	}
+
+	if config.Type == "" {
+		config.Type = WatermarkTypeText
+	}
+	if config.Position == "" {
+		config.Position = utils.WatermarkBottomRight
+	}
+	if config.Scale <= 0 || config.Scale > 1 {
+		config.Scale = 0.2
+	}
+
+	return config, nil
+}
+
+// ShouldApply 判断本次上传是否需要添加水印，request 为 nil 时按配置决定
+func (s *WatermarkServiceImpl) ShouldApply(config *models.WatermarkConfig, request *bool) bool {
+	if request != nil {
+		return *request
+	}
+	return config.Enabled
+}
+
+// Apply 为图片内容添加水印，返回重新编码后的内容
+// 只处理 jpeg 和 png，gif 等格式原样返回
+func (s *WatermarkServiceImpl) Apply(config *models.WatermarkConfig, content []byte, format string) ([]byte, error) {
+	if format != "jpg" && format != "jpeg" && format != "png" {
+		return content, nil
+	}
+
+	img, err := utils.DecodeImage(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %v", err)
+	}
+
+	var mark image.Image
+	switch config.Type {
+	case WatermarkTypeText:
+		if config.Text == "" {
+			return content, nil
+		}
+		mark = utils.RenderText(config.Text, utils.ParseHexColor(config.Color, color.White))
+	case WatermarkTypeImage:
+		if mark, err = s.loadLogo(config); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported watermark type: %s", config.Type)
+	}
+
+	result := utils.ApplyWatermark(img, mark, config.Position, config.Opacity, config.Scale, config.Margin)
+	return utils.EncodeImage(result, format)
+}
+
+// loadLogo 读取已上传到 PicHub 的水印图片
+// 用户配置只能引用自己的文件，系统配置由管理员设置，可以引用任意用户上传的文件
+func (s *WatermarkServiceImpl) loadLogo(config *models.WatermarkConfig) (image.Image, error) {
+	query := database.DB.Where("id = ?", config.FileID)
+	if config.OwnerID != 0 {
+		query = query.Where("user_id = ?", config.OwnerID)
+	}
+
+	var file models.File
+	if err := query.First(&file).Error; err != nil {
+		return nil, fmt.Errorf("watermark file not found")
+	}
+
+	content, err := FileService.ReadContent(&file)
+	if err != nil {
+		return nil, err
+	}
+
+	return utils.DecodeImage(content)
+}
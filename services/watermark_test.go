@@ -0,0 +1,92 @@
+package services
+
+import (
+	"bytes"
+	"database/sql/driver"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"testing"
+)
+
+func solidPNG(t *testing.T, width, height int, c color.Color) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(img, img.Bounds(), &image.Uniform{C: c}, image.Point{}, draw.Src)
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("encode png: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// 用户没有水印配置时使用系统配置，系统配置引用的水印图片属于管理员，其他用户上传时也能读取
+func TestWatermarkSystemConfigLogo(t *testing.T) {
+	db := newFakeDB(t)
+	gh := newFakeGithub(t)
+	gh.files["logos/logo.png"] = solidPNG(t, 10, 10, color.RGBA{R: 255, A: 255})
+
+	configColumns := []string{"id", "user_id", "type", "name", "value"}
+	db.On("`config`", []interface{}{"watermark", 0}, configColumns,
+		[]driver.Value{1, 0, "watermark", "enabled", "true"},
+		[]driver.Value{2, 0, "watermark", "type", "image"},
+		[]driver.Value{3, 0, "watermark", "file_id", "42"},
+		[]driver.Value{4, 0, "watermark", "opacity", "1"},
+		[]driver.Value{5, 0, "watermark", "margin", "0"},
+	)
+	// 系统配置按文件 ID 查询，不限制用户，最后一个参数为 LIMIT
+	db.On("FROM `pic_files` WHERE id = ? AND `pic_files`.`deleted_at` IS NULL", []interface{}{42, 1}, []string{"id", "user_id", "repo_id", "url"},
+		[]driver.Value{42, 1, 7, "logos/logo.png"},
+	)
+	db.On("FROM `pic_repositories`", []interface{}{7, 1}, []string{"id", "user_id", "repo_url", "repo_branch"},
+		[]driver.Value{7, 1, "https://github.com/o/r", "main"},
+	)
+
+	config, err := WatermarkService.GetConfig(5)
+	if err != nil {
+		t.Fatalf("get config: %v", err)
+	}
+	if config.OwnerID != 0 || config.Type != WatermarkTypeImage || config.FileID != 42 {
+		t.Fatalf("unexpected config: %+v", config)
+	}
+
+	result, err := WatermarkService.Apply(config, solidPNG(t, 100, 100, color.White), "png")
+	if err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	img, err := png.Decode(bytes.NewReader(result))
+	if err != nil {
+		t.Fatalf("decode result: %v", err)
+	}
+	if r, g, b, _ := img.At(99, 99).RGBA(); r>>8 != 255 || g>>8 != 0 || b>>8 != 0 {
+		t.Fatalf("watermark not applied, bottom-right pixel is %d,%d,%d", r>>8, g>>8, b>>8)
+	}
+}
+
+// 用户自己的配置只能引用自己的文件
+func TestWatermarkUserConfigLogoOwnership(t *testing.T) {
+	db := newFakeDB(t)
+	newFakeGithub(t)
+
+	db.On("`config`", []interface{}{"watermark", 5}, []string{"id", "user_id", "type", "name", "value"},
+		[]driver.Value{1, 5, "watermark", "type", "image"},
+		[]driver.Value{2, 5, "watermark", "file_id", "42"},
+	)
+	// 文件属于用户 1，带 user_id = 5 条件的查询没有结果
+	db.On("FROM `pic_files` WHERE id = ? AND user_id = ?", []interface{}{42, 1, 1}, []string{"id", "user_id", "repo_id", "url"},
+		[]driver.Value{42, 1, 7, "logos/logo.png"},
+	)
+
+	config, err := WatermarkService.GetConfig(5)
+	if err != nil {
+		t.Fatalf("get config: %v", err)
+	}
+	if config.OwnerID != 5 {
+		t.Fatalf("owner = %d, want 5", config.OwnerID)
+	}
+	if _, err := WatermarkService.Apply(config, solidPNG(t, 100, 100, color.White), "png"); err == nil {
+		t.Fatal("expected error when the logo belongs to another user")
+	}
+}
@@ -44,7 +44,7 @@ func (s *WebhookServiceImpl) HandlePush(payload *models.WebhookPayload) error {
 	}
 
 	// 开启事务
-	return database.DB.Transaction(func(tx *gorm.DB) error {
+	err := database.DB.Transaction(func(tx *gorm.DB) error {
 		// 处理所有提交
 		for _, commit := range payload.Commits {
 			// 处理新增文件
@@ -64,6 +64,12 @@ func (s *WebhookServiceImpl) HandlePush(payload *models.WebhookPayload) error {
 		}
 		return nil
 	})
+	if err != nil {
+		return err
+	}
+
+	// 推送会直接增删文件记录，重新统计用量
+	return StorageService.Recalculate(repo.UserID)
 }
 
 // handleAddedFiles 处理新增文件
@@ -96,7 +102,8 @@ func (s *WebhookServiceImpl) handleRemovedFiles(tx *gorm.DB, files []string, rep
 			continue
 		}
 
-		if err := tx.Where("repo_id = ? AND filename = ?",
+		// 仓库中的文件已被删除，直接删除记录
+		if err := tx.Unscoped().Where("repo_id = ? AND filename = ?",
 			repo.ID, getFilenameFromPath(filePath)).
 			Delete(&models.File{}).Error; err != nil {
 			return fmt.Errorf("failed to delete file record: %v", err)